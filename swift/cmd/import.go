@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/mbartnicki80/swift/internal/store"
+	"io"
+	"log"
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+func logReport(report parser.Report) {
+	for _, rejected := range report.Rejected {
+		log.Printf("row %d rejected (%s): %s", rejected.Row, rejected.SwiftCode, rejected.Reason)
+	}
+	if omitted := report.RejectedRows - len(report.Rejected); omitted > 0 {
+		log.Printf("%d more rejected rows not listed", omitted)
+	}
+	for _, warning := range report.Warnings {
+		log.Printf("row %d warning (%s): %s", warning.Row, warning.SwiftCode, warning.Reason)
+	}
+	if omitted := report.WarningCount - len(report.Warnings); omitted > 0 {
+		log.Printf("%d more warnings not listed", omitted)
+	}
+}
+
+type importJob struct {
+	path      string
+	format    parser.Format
+	options   parser.Options
+	mode      string
+	batchSize int
+	operator  string
+}
+
+// parseOptions builds the parser options from the -sheet and -aliases style
+// flags shared by import and serve's seeding.
+func parseOptions(sheet, aliases string) (parser.Options, error) {
+	parsed, err := parser.ParseAliases(aliases)
+	if err != nil {
+		return parser.Options{}, err
+	}
+	return parser.Options{Sheet: sheet, Aliases: parsed}, nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func defaultOperator() string {
+	if name := os.Getenv("IMPORT_OPERATOR"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+func runImport(ctx context.Context, db *sql.DB, job importJob) error {
+	if job.mode != "insert" && job.mode != "sync" {
+		return fmt.Errorf("unknown import mode %q", job.mode)
+	}
+	if job.format == "" {
+		format, err := parser.FormatFromPath(job.path)
+		if err != nil {
+			return err
+		}
+		job.format = format
+	}
+
+	checksum, err := fileChecksum(job.path)
+	if err != nil {
+		return err
+	}
+
+	importID, err := store.StartImport(ctx, db, model.Import{
+		SourceFilename: filepath.Base(job.path),
+		Checksum:       checksum,
+		Format:         string(job.format),
+		Mode:           job.mode,
+		Operator:       job.operator,
+	})
+	if err != nil {
+		return err
+	}
+
+	validator := parser.NewValidator()
+	err = importRows(ctx, db, job, importID, validator)
+
+	report := validator.Report()
+	status := store.ImportSucceeded
+	if err != nil {
+		status = store.ImportFailed
+	}
+	// Record the outcome even when the import was interrupted.
+	finishErr := store.FinishImport(context.WithoutCancel(ctx), db, importID, status,
+		report.AcceptedRows+report.RejectedRows, report.AcceptedRows, report.RejectedRows)
+	if err != nil {
+		return err
+	}
+	if finishErr != nil {
+		return finishErr
+	}
+
+	log.Printf("import %d recorded", importID)
+	return nil
+}
+
+func importRows(ctx context.Context, db *sql.DB, job importJob, importID int64, validator *parser.Validator) error {
+	switch job.mode {
+	case "insert":
+		inserter := store.NewBatchInserter(db, job.batchSize)
+		inserter.SetImportID(importID)
+		err := parser.StreamFile(job.path, job.format, job.options, func(record parser.SwiftRecord) error {
+			record, ok := validator.Check(record)
+			if !ok {
+				return nil
+			}
+			return inserter.Add(ctx, record)
+		})
+		if err != nil {
+			return err
+		}
+		if err := inserter.Close(ctx); err != nil {
+			return err
+		}
+
+		report := validator.Report()
+		logReport(report)
+		log.Printf("imported %d rows, rejected %d, skipped %d deleted", report.AcceptedRows, report.RejectedRows, report.Skipped)
+		return nil
+
+	case "sync":
+		syncer, err := store.NewSyncer(ctx, db)
+		if err != nil {
+			return err
+		}
+		syncer.SetImportID(importID)
+		err = parser.StreamFile(job.path, job.format, job.options, func(record parser.SwiftRecord) error {
+			record, ok := validator.Check(record)
+			if !ok {
+				return nil
+			}
+			return syncer.Add(ctx, record)
+		})
+		if err != nil {
+			syncer.Rollback()
+			return err
+		}
+
+		report := validator.Report()
+		logReport(report)
+		if report.RejectedRows > 0 {
+			syncer.Rollback()
+			return fmt.Errorf("sync aborted: %d rejected rows would be deleted from the database", report.RejectedRows)
+		}
+
+		summary, err := syncer.Apply(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("synced %d rows: %d inserted, %d updated, %d deleted",
+			report.AcceptedRows, len(summary.Inserted), len(summary.Updated), len(summary.Deleted))
+		return nil
+	}
+
+	return fmt.Errorf("unknown import mode %q", job.mode)
+}
+
+func runDryRun(ctx context.Context, db *sql.DB, path string, format parser.Format, options parser.Options, output string) error {
+	validator := parser.NewValidator()
+	diff, err := store.DryRun(ctx, db, func(add func(parser.SwiftRecord) error) error {
+		return parser.StreamFile(path, format, options, func(record parser.SwiftRecord) error {
+			record, ok := validator.Check(record)
+			if !ok {
+				return nil
+			}
+			return add(record)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	report := validator.Report()
+	logReport(report)
+
+	if output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]any{"diff": diff, "report": report})
+	}
+	return diff.WriteText(os.Stdout)
+}
+
+func importCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	importFormat := fs.String("format", "", "import format: xlsx, csv, ndjson or bicdir (detected from the file extension if empty)")
+	importMode := fs.String("mode", "insert", "import mode: insert keeps existing rows, sync applies inserts, updates and deletes")
+	dryRun := fs.Bool("dry-run", false, "print the changes a sync import would make and exit without committing")
+	output := fs.String("output", "text", "dry-run output format: text or json")
+	batchSize := fs.Int("batch-size", store.DefaultBatchSize, "number of rows inserted per transaction")
+	operator := fs.String("operator", defaultOperator(), "name of the person or job running the import")
+	sheet := fs.String("sheet", os.Getenv("IMPORT_SHEET"), "xlsx sheet to read (the first sheet if empty)")
+	aliases := fs.String("aliases", os.Getenv("IMPORT_ALIASES"), `extra header names per column, e.g. "SWIFT CODE=CODE,KOD;NAME=BANK"`)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: swift import [flags] <file>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	path := fs.Arg(0)
+	options, err := parseOptions(*sheet, *aliases)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *dryRun {
+		return runDryRun(ctx, db, path, parser.Format(*importFormat), options, *output)
+	}
+	return runImport(ctx, db, importJob{
+		path:      path,
+		format:    parser.Format(*importFormat),
+		options:   options,
+		mode:      *importMode,
+		batchSize: *batchSize,
+		operator:  *operator,
+	})
+}
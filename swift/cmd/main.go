@@ -1,24 +1,18 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
-	"github.com/mbartnicki80/swift/internal/api"
-	"github.com/mbartnicki80/swift/internal/parser"
-	"github.com/mbartnicki80/swift/internal/store"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
-func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env")
-	}
-
+func openDB() (*sql.DB, error) {
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
 	dbUser := os.Getenv("DB_USER")
@@ -28,43 +22,51 @@ func main() {
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		dbHost, dbPort, dbUser, dbPass, dbName)
 
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return sql.Open("postgres", connStr)
+}
 
-	defer func(db *sql.DB) {
-		err := db.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}(db)
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: swift <command> [flags]
 
-	records, err := parser.ParseFromExcel("swift_codes.xlsx")
+commands:
+  serve    start the HTTP API (default)
+  import   load a SWIFT codes file into the database
+  migrate  apply, revert or list schema migrations (up, down, status)`)
+}
+
+func main() {
+	err := godotenv.Load()
 	if err != nil {
-		log.Fatal(err)
+		log.Println("no .env file found, using environment variables")
 	}
 
-	err = store.InsertRowsToDatabase(db, records)
-	if err != nil {
-		log.Fatal(err)
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
 	}
 
-	router := gin.Default()
+	// Interrupting a command cancels its queries instead of killing the
+	// process halfway through a transaction.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	v1 := router.Group("/v1")
-	{
-		swift := v1.Group("/swift-codes")
-		{
-			swift.GET("/:swiftCode", api.GetSwiftCodeHandler(db))
-			swift.GET("/country/:countryISO2", api.GetSwiftCodesByCountryHandler(db))
-			swift.POST("", api.CreateSwiftCodeHandler(db))
-			swift.DELETE("/:swiftCode", api.DeleteSwiftCodeHandler(db))
-		}
+	switch command {
+	case "serve":
+		err = serveCommand(ctx, args)
+	case "import":
+		err = importCommand(ctx, args)
+	case "migrate":
+		err = migrateCommand(ctx, args)
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		usage()
+		os.Exit(2)
 	}
 
-	err = router.Run(":8080")
 	if err != nil {
-		return
+		stop()
+		log.Fatal(err)
 	}
 }
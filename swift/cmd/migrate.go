@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/store/sqlite"
+	"github.com/mbartnicki80/swift/migrations"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+// loadMigrations returns the migrations embedded for backend, or the ones in
+// dir when it is set.
+func loadMigrations(backend, dir string) ([]store.Migration, error) {
+	if dir != "" {
+		return store.LoadMigrations(os.DirFS(dir))
+	}
+	switch backend {
+	case "postgres":
+		return store.LoadMigrations(migrations.FS)
+	case "sqlite":
+		return sqlite.Migrations()
+	}
+	return nil, fmt.Errorf("store backend %q has no migrations", backend)
+}
+
+func writeStatus(statuses []store.MigrationStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		switch {
+		case status.Unknown:
+			fmt.Fprintf(w, "%s\tunknown to this binary\t%s\n", status.Migration, status.AppliedAt.Format("2006-01-02 15:04:05"))
+		case status.AppliedAt != nil:
+			fmt.Fprintf(w, "%s\tapplied\t%s\n", status.Migration, status.AppliedAt.Format("2006-01-02 15:04:05"))
+		default:
+			fmt.Fprintf(w, "%s\tpending\t\n", status.Migration)
+		}
+	}
+	return w.Flush()
+}
+
+func migrateCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	backend := fs.String("store", envOr("STORE_BACKEND", "postgres"), "database to migrate: postgres or sqlite")
+	sqlitePath := fs.String("sqlite-path", envOr("SQLITE_PATH", "swift.db"), "database file used by the sqlite backend")
+	dir := fs.String("dir", os.Getenv("MIGRATIONS_DIR"), "read migrations from this directory instead of the ones built into the binary")
+	to := fs.Int64("to", 0, "up: stop after this version (default: latest)")
+	steps := fs.Int("steps", 1, "down: number of migrations to revert")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: swift migrate [flags] [up|down|status] [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	action := "up"
+	if fs.NArg() > 0 {
+		action = fs.Arg(0)
+		fs.Parse(fs.Args()[1:])
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	loaded, err := loadMigrations(*backend, *dir)
+	if err != nil {
+		return err
+	}
+
+	var db *sql.DB
+	switch *backend {
+	case "sqlite":
+		db, err = sqlite.Open(*sqlitePath)
+	default:
+		db, err = openDB()
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator := store.NewMigrator(db, loaded)
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx, *to)
+		for _, m := range applied {
+			log.Printf("applied %s", m)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			log.Printf("reverted %s", m)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return writeStatus(statuses)
+	}
+	fs.Usage()
+	os.Exit(2)
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/api"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/store/memory"
+	"github.com/mbartnicki80/swift/internal/store/sqlite"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// shutdownTimeout is how long in-flight requests get to finish once the
+// server is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
+func setupRouter(codes store.SwiftCodeRepository, imports store.ImportRepository) *gin.Engine {
+	router := gin.Default()
+
+	v1 := router.Group("/v1")
+	{
+		swift := v1.Group("/swift-codes")
+		{
+			swift.GET("/search", api.SearchSwiftCodesHandler(codes))
+			swift.GET("/match", api.MatchBankNameHandler(codes))
+			swift.GET("/export", api.ExportSwiftCodesHandler(codes))
+			swift.GET("/:swiftCode", api.GetSwiftCodeHandler(codes))
+			swift.GET("/country/:countryISO2", api.GetSwiftCodesByCountryHandler(codes))
+			swift.POST("", api.CreateSwiftCodeHandler(codes))
+			swift.POST("/lookup", api.LookupSwiftCodesHandler(codes))
+			swift.POST("/bulk", api.BulkCreateSwiftCodesHandler(codes))
+			swift.POST("/bulk/delete", api.BulkDeleteSwiftCodesHandler(codes))
+			swift.PUT("/:swiftCode", api.UpdateSwiftCodeHandler(codes))
+			swift.PATCH("/:swiftCode", api.PatchSwiftCodeHandler(codes))
+			swift.DELETE("/:swiftCode", api.DeleteSwiftCodeHandler(codes))
+		}
+
+		admin := v1.Group("/admin")
+		{
+			admin.GET("/imports", api.GetImportsHandler(imports))
+			admin.GET("/imports/:id", api.GetImportHandler(imports))
+			admin.POST("/imports/dry-run", api.DryRunImportHandler(imports))
+		}
+	}
+
+	return router
+}
+
+// listen serves handler until ctx is cancelled, then shuts down gracefully.
+func listen(ctx context.Context, addr string, handler http.Handler) error {
+	server := &http.Server{Addr: addr, Handler: handler}
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", addr)
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// readSeed parses and validates a seed file for the backends that load it
+// in one go instead of through runImport.
+func readSeed(path string, format parser.Format, options parser.Options) ([]parser.SwiftRecord, error) {
+	if format == "" {
+		detected, err := parser.FormatFromPath(path)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	validator := parser.NewValidator()
+	var records []parser.SwiftRecord
+	err := parser.StreamFile(path, format, options, func(record parser.SwiftRecord) error {
+		record, ok := validator.Check(record)
+		if ok {
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := validator.Report()
+	logReport(report)
+	log.Printf("read %d seed rows, rejected %d", len(records), report.RejectedRows)
+	return records, nil
+}
+
+func serveCommand(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", envOr("HTTP_ADDR", ":8080"), "address the HTTP server listens on")
+	backend := fs.String("store", envOr("STORE_BACKEND", "postgres"), "storage backend: postgres, sqlite or memory")
+	sqlitePath := fs.String("sqlite-path", envOr("SQLITE_PATH", "swift.db"), "database file used by the sqlite backend")
+	seedFile := fs.String("seed", os.Getenv("SEED_FILE"), "optional SWIFT codes file imported before the server starts")
+	seedFormat := fs.String("seed-format", os.Getenv("SEED_FORMAT"), "seed file format (detected from the file extension if empty)")
+	seedMode := fs.String("seed-mode", envOr("SEED_MODE", "insert"), "seed import mode: insert or sync")
+	seedSheet := fs.String("seed-sheet", os.Getenv("SEED_SHEET"), "xlsx sheet of the seed file (the first sheet if empty)")
+	seedAliases := fs.String("seed-aliases", os.Getenv("SEED_ALIASES"), `extra header names per column, e.g. "SWIFT CODE=CODE,KOD;NAME=BANK"`)
+	allowOutdated := fs.Bool("allow-outdated-schema", os.Getenv("ALLOW_OUTDATED_SCHEMA") == "true", "start even if the database is missing migrations")
+	queryTimeout := fs.Duration("query-timeout", 10*time.Second, "cancel database queries that run longer than this (0 disables the limit)")
+	if value := os.Getenv("QUERY_TIMEOUT"); value != "" {
+		if err := fs.Set("query-timeout", value); err != nil {
+			return fmt.Errorf("QUERY_TIMEOUT: %w", err)
+		}
+	}
+	fs.Parse(args)
+	seedOptions, err := parseOptions(*seedSheet, *seedAliases)
+	if err != nil {
+		return err
+	}
+
+	switch *backend {
+	case "memory":
+		// The store always starts empty, so insert and sync seeding are the same.
+		repo := memory.New()
+		if *seedFile != "" {
+			records, err := readSeed(*seedFile, parser.Format(*seedFormat), seedOptions)
+			if err != nil {
+				return err
+			}
+			log.Printf("seeded %d rows", repo.Load(records))
+		}
+		return listen(ctx, *addr, setupRouter(repo, repo))
+	case "sqlite":
+		return serveSQLite(ctx, *addr, *sqlitePath, *seedFile, parser.Format(*seedFormat), seedOptions, *seedMode, *allowOutdated, *queryTimeout)
+	case "postgres":
+	default:
+		return fmt.Errorf("unknown store backend %q", *backend)
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := checkSchema(ctx, db, "postgres", *allowOutdated); err != nil {
+		return err
+	}
+
+	if *seedFile != "" {
+		err = runImport(ctx, db, importJob{
+			path:      *seedFile,
+			format:    parser.Format(*seedFormat),
+			options:   seedOptions,
+			mode:      *seedMode,
+			batchSize: store.DefaultBatchSize,
+			operator:  defaultOperator(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	repo := store.NewPostgresRepository(db)
+	repo.SetQueryTimeout(*queryTimeout)
+	return listen(ctx, *addr, setupRouter(repo, repo))
+}
+
+func serveSQLite(ctx context.Context, addr, path, seedFile string, seedFormat parser.Format, seedOptions parser.Options, seedMode string, allowOutdated bool, queryTimeout time.Duration) error {
+	if seedFile != "" && seedMode != "insert" {
+		return fmt.Errorf("the sqlite store only supports insert seeding, got %q", seedMode)
+	}
+
+	db, err := sqlite.Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := checkSchema(ctx, db, "sqlite", allowOutdated); err != nil {
+		return err
+	}
+
+	repo := sqlite.New(db)
+	if seedFile != "" {
+		records, err := readSeed(seedFile, seedFormat, seedOptions)
+		if err != nil {
+			return err
+		}
+		inserted, err := repo.Load(ctx, records)
+		if err != nil {
+			return err
+		}
+		log.Printf("seeded %d rows", inserted)
+	}
+	repo.SetQueryTimeout(queryTimeout)
+	return listen(ctx, addr, setupRouter(repo, repo))
+}
+
+// checkSchema refuses to serve from a database that is missing migrations,
+// because handlers would fail on columns or indexes that are not there yet.
+func checkSchema(ctx context.Context, db *sql.DB, backend string, allowOutdated bool) error {
+	loaded, err := loadMigrations(backend, "")
+	if err != nil {
+		return err
+	}
+	err = store.NewMigrator(db, loaded).Check(ctx)
+	var outdated *store.OutdatedSchemaError
+	if errors.As(err, &outdated) {
+		if allowOutdated {
+			log.Printf("warning: %v", err)
+			return nil
+		}
+		return fmt.Errorf("%w; run `swift migrate -store %s up` or pass -allow-outdated-schema", err, backend)
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/mbartnicki80/swift/internal/store"
+)
+
+func importProblem(err error) *Problem {
+	p := NewProblem(http.StatusUnprocessableEntity, ProblemUnprocessable, "Import file could not be parsed", err.Error())
+	var missing *parser.MissingColumnsError
+	if errors.As(err, &missing) {
+		for _, column := range missing.Columns {
+			p.Errors = append(p.Errors, FieldError{Field: string(column), Message: "column is missing"})
+		}
+	}
+	return p
+}
+
+func DryRunImportHandler(repo store.ImportRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header, err := c.FormFile("file")
+		if err != nil {
+			respondProblem(c, validationProblem([]FieldError{{Field: "file", Message: "is required"}}))
+			return
+		}
+
+		format := parser.Format(c.PostForm("format"))
+		if format == "" {
+			format, err = parser.FormatFromPath(header.Filename)
+			if err != nil {
+				respondProblem(c, validationProblem([]FieldError{{Field: "format", Message: err.Error()}}))
+				return
+			}
+		}
+		decoder, err := parser.NewDecoder(format, parser.Options{Sheet: c.PostForm("sheet")})
+		if err != nil {
+			respondProblem(c, validationProblem([]FieldError{{Field: "format", Message: err.Error()}}))
+			return
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			respondProblem(c, badRequestProblem("Could not read import file"))
+			return
+		}
+		defer file.Close()
+
+		validator := parser.NewValidator()
+		var decodeErr, storeErr error
+		diff, err := repo.DryRun(c.Request.Context(), func(add func(parser.SwiftRecord) error) error {
+			decodeErr = decoder.Decode(file, func(record parser.SwiftRecord) error {
+				record, ok := validator.Check(record)
+				if !ok {
+					return nil
+				}
+				storeErr = add(record)
+				return storeErr
+			})
+			return decodeErr
+		})
+		if err != nil {
+			if decodeErr != nil && storeErr == nil {
+				respondProblem(c, importProblem(decodeErr))
+				return
+			}
+			respondProblem(c, storeProblem(err, "Could not compute import diff"))
+			return
+		}
+
+		report := validator.Report()
+		if c.Query("output") == "text" {
+			var b strings.Builder
+			if err := diff.WriteText(&b); err != nil {
+				respondProblem(c, internalProblem(""))
+				return
+			}
+			for _, rejected := range report.Rejected {
+				fmt.Fprintf(&b, "! row %d (%s): %s\n", rejected.Row, rejected.SwiftCode, rejected.Reason)
+			}
+			if omitted := report.RejectedRows - len(report.Rejected); omitted > 0 {
+				fmt.Fprintf(&b, "! %d more rejected rows\n", omitted)
+			}
+			c.String(http.StatusOK, b.String())
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"diff":   diff,
+			"report": report,
+		})
+	}
+}
+
+func GetImportsHandler(repo store.ImportRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		imports, err := repo.Imports(c.Request.Context())
+		if err != nil {
+			respondProblem(c, storeProblem(err, ""))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"imports": imports})
+	}
+}
+
+func GetImportHandler(repo store.ImportRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondProblem(c, validationProblem([]FieldError{{Field: "id", Message: "must be an integer"}}))
+			return
+		}
+		imp, err := repo.Import(c.Request.Context(), id)
+		if errors.Is(err, store.ErrNotFound) {
+			respondProblem(c, notFoundProblem("Import "+c.Param("id")+" not found"))
+			return
+		}
+		if err != nil {
+			respondProblem(c, storeProblem(err, ""))
+			return
+		}
+		c.JSON(http.StatusOK, imp)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func uploadRequest(t *testing.T, url, filename, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, url, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestDryRunImportHandler(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		router := gin.New()
+		router.POST("/dry-run", DryRunImportHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/dry-run", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("missing columns", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TEMP TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		router := gin.New()
+		router.POST("/dry-run", DryRunImportHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, uploadRequest(t, "/dry-run", "codes.csv", "SWIFT CODE\nPKOPPLPWXXX\n"))
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, ProblemUnprocessable, problem.Type)
+		assert.Contains(t, problem.Detail, "missing required columns")
+		assert.Contains(t, problem.Errors, FieldError{Field: "COUNTRY ISO2 CODE", Message: "column is missing"})
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns diff and report", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TEMP TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO incoming_swift_codes").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT swift_code FROM incoming_swift_codes").
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code"}).AddRow("PKOPPLPWXXX"))
+		mock.ExpectQuery("SELECT swift_code FROM swift_codes").
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code"}))
+		mock.ExpectQuery("SELECT s.swift_code").
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code"}))
+		mock.ExpectQuery("SELECT branches.swift_code").
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code", "headquarter", "swift_code"}))
+		mock.ExpectRollback()
+
+		router := gin.New()
+		router.POST("/dry-run", DryRunImportHandler(store.NewPostgresRepository(db)))
+
+		csv := "SWIFT CODE,NAME,COUNTRY ISO2 CODE,COUNTRY NAME\nPKOPPLPWXXX,PKO,PL,POLAND\nBAD,PKO,PL,POLAND\n"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, uploadRequest(t, "/dry-run", "codes.csv", csv))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Diff struct {
+				Inserted []string `json:"inserted"`
+			} `json:"diff"`
+			Report struct {
+				Accepted int `json:"accepted"`
+				Rejected []struct {
+					Row int `json:"row"`
+				} `json:"rejected"`
+			} `json:"report"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []string{"PKOPPLPWXXX"}, response.Diff.Inserted)
+		assert.Equal(t, 1, response.Report.Accepted)
+		require.Len(t, response.Report.Rejected, 1)
+		assert.Equal(t, 3, response.Report.Rejected[0].Row)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/validation"
+)
+
+const MaxBulkItems = 10000
+
+const (
+	BulkAtomic     = "atomic"
+	BulkBestEffort = "best-effort"
+)
+
+type BulkItemResult struct {
+	Index     int      `json:"index"`
+	SwiftCode string   `json:"swiftCode"`
+	Status    int      `json:"status"`
+	Error     *Problem `json:"error,omitempty"`
+}
+
+type deleteItem struct {
+	SwiftCode string `json:"swiftCode"`
+}
+
+func (d *deleteItem) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &d.SwiftCode); err == nil {
+		return nil
+	}
+	type plain deleteItem
+	return json.Unmarshal(data, (*plain)(d))
+}
+
+func isNDJSON(c *gin.Context) bool {
+	contentType := c.ContentType()
+	return contentType == "application/x-ndjson" || contentType == "application/ndjson"
+}
+
+// decodeBulkItems reads a JSON array, or one JSON value per line when the
+// request is sent as NDJSON.
+func decodeBulkItems[T any](c *gin.Context) ([]T, error) {
+	decoder := json.NewDecoder(c.Request.Body)
+	if !isNDJSON(c) {
+		var items []T
+		err := decoder.Decode(&items)
+		return items, err
+	}
+
+	var items []T
+	for {
+		var item T
+		err := decoder.Decode(&item)
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+func bulkMode(c *gin.Context) (bool, []FieldError) {
+	switch c.DefaultQuery("mode", BulkAtomic) {
+	case BulkAtomic:
+		return true, nil
+	case BulkBestEffort:
+		return false, nil
+	default:
+		return false, []FieldError{{Field: "mode", Message: "must be atomic or best-effort"}}
+	}
+}
+
+func checkBulkSize(count int) []FieldError {
+	if count == 0 || count > MaxBulkItems {
+		return []FieldError{{Field: "items", Message: fmt.Sprintf("must contain between 1 and %d items", MaxBulkItems)}}
+	}
+	return nil
+}
+
+func storeErrorProblem(swiftCode string, err error) *Problem {
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		return conflictProblem(ProblemConflict, "SWIFT code "+swiftCode+" already exists")
+	case errors.Is(err, store.ErrNotFound):
+		return notFoundProblem("SWIFT code " + swiftCode + " not found")
+	case errors.Is(err, store.ErrHasBranches):
+		return conflictProblem(ProblemHasBranches, "SWIFT code "+swiftCode+" has branches and cannot be deleted")
+	default:
+		return storeProblem(err, "")
+	}
+}
+
+func rolledBackProblem() *Problem {
+	return NewProblem(http.StatusFailedDependency, ProblemRolledBack, "Rolled back", "Another item in the atomic request failed")
+}
+
+// respondBulk merges validation failures with the store outcome for the items
+// that reached the store; pending maps store positions back to item indexes.
+func respondBulk(c *gin.Context, atomic bool, results []BulkItemResult, pending []int, outcome store.BulkResult, successStatus int) {
+	for pos, i := range pending {
+		if err := outcome.Errors[pos]; err != nil {
+			results[i].Error = storeErrorProblem(results[i].SwiftCode, err)
+			results[i].Status = results[i].Error.Status
+		} else if outcome.Committed {
+			results[i].Status = successStatus
+		}
+	}
+
+	failed := 0
+	for i := range results {
+		if results[i].Error != nil {
+			failed++
+		}
+	}
+	committed := outcome.Committed && !(atomic && failed > 0)
+	if !committed {
+		for i := range results {
+			if results[i].Error == nil {
+				results[i].Error = rolledBackProblem()
+				results[i].Status = results[i].Error.Status
+			}
+		}
+	}
+
+	status := http.StatusOK
+	switch {
+	case !committed:
+		status = http.StatusUnprocessableEntity
+	case failed > 0:
+		status = http.StatusMultiStatus
+	}
+
+	mode := BulkBestEffort
+	if atomic {
+		mode = BulkAtomic
+	}
+	c.JSON(status, gin.H{
+		"mode":      mode,
+		"committed": committed,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   results,
+	})
+}
+
+func BulkCreateSwiftCodesHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		atomic, errs := bulkMode(c)
+		if len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+		codes, err := decodeBulkItems[model.SwiftCode](c)
+		if err != nil {
+			respondProblem(c, bindingProblem(err))
+			return
+		}
+		if errs := checkBulkSize(len(codes)); len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+
+		results := make([]BulkItemResult, len(codes))
+		var valid []model.SwiftCode
+		var pending []int
+		for i := range codes {
+			errs := validation.SwiftCode(&codes[i])
+			results[i] = BulkItemResult{Index: i, SwiftCode: codes[i].SwiftCode}
+			if len(errs) > 0 {
+				results[i].Error = validationProblem(errs)
+				results[i].Status = http.StatusBadRequest
+				continue
+			}
+			valid = append(valid, codes[i])
+			pending = append(pending, i)
+		}
+
+		var outcome store.BulkResult
+		if len(valid) > 0 && (!atomic || len(valid) == len(codes)) {
+			outcome, err = repo.BulkCreate(c.Request.Context(), valid, atomic)
+			if err != nil {
+				respondProblem(c, storeProblem(err, "Could not insert SWIFT codes"))
+				return
+			}
+		} else {
+			pending = nil
+		}
+		respondBulk(c, atomic, results, pending, outcome, http.StatusCreated)
+	}
+}
+
+func BulkDeleteSwiftCodesHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		atomic, errs := bulkMode(c)
+		if len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+		items, err := decodeBulkItems[deleteItem](c)
+		if err != nil {
+			respondProblem(c, bindingProblem(err))
+			return
+		}
+		if errs := checkBulkSize(len(items)); len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+
+		results := make([]BulkItemResult, len(items))
+		codes := make([]string, len(items))
+		pending := make([]int, len(items))
+		for i, item := range items {
+			codes[i] = validation.NormalizeBIC(item.SwiftCode)
+			results[i] = BulkItemResult{Index: i, SwiftCode: codes[i]}
+			pending[i] = i
+		}
+
+		outcome, err := repo.BulkDelete(c.Request.Context(), codes, atomic)
+		if err != nil {
+			respondProblem(c, storeProblem(err, "Could not delete SWIFT codes"))
+			return
+		}
+		respondBulk(c, atomic, results, pending, outcome, http.StatusOK)
+	}
+}
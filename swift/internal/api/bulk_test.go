@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bulkResponse struct {
+	Mode      string           `json:"mode"`
+	Committed bool             `json:"committed"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+func bulkRequest(t *testing.T, router *gin.Engine, path, contentType, body string) (int, bulkResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	router.ServeHTTP(w, req)
+
+	var response bulkResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+func TestBulkCreateSwiftCodesHandler(t *testing.T) {
+	t.Run("atomic request with an invalid item is not sent to the store", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		router := gin.New()
+		router.POST("/bulk", BulkCreateSwiftCodesHandler(store.NewPostgresRepository(db)))
+
+		body := `[{"swiftCode": "PKOPPLPWXXX", "countryISO2": "PL", "bankName": "PKO", "isHeadquarter": true},
+		          {"swiftCode": "ABC", "countryISO2": "PL", "bankName": "PKO"}]`
+		status, response := bulkRequest(t, router, "/bulk", "application/json", body)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, status)
+		assert.False(t, response.Committed)
+		assert.Equal(t, http.StatusFailedDependency, response.Results[0].Status)
+		assert.Equal(t, ProblemRolledBack, response.Results[0].Error.Type)
+		assert.Equal(t, http.StatusBadRequest, response.Results[1].Status)
+		assert.Equal(t, "swiftCode", response.Results[1].Error.Errors[0].Field)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("best effort NDJSON reports per item status", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO swift_codes").
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code"}).AddRow("PKOPPLPWXXX"))
+		mock.ExpectExec("RELEASE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE branches").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		router := gin.New()
+		router.POST("/bulk", BulkCreateSwiftCodesHandler(store.NewPostgresRepository(db)))
+
+		body := `{"swiftCode": "pkopplpw", "countryISO2": "PL", "bankName": "PKO", "isHeadquarter": true}
+{"swiftCode": "BREXPLPWXXX", "countryISO2": "PL", "bankName": "MBANK", "isHeadquarter": true}
+{"swiftCode": "BREXPLPWXXX", "countryISO2": "DE", "bankName": "MBANK", "isHeadquarter": true}
+`
+		status, response := bulkRequest(t, router, "/bulk?mode=best-effort", "application/x-ndjson", body)
+
+		assert.Equal(t, http.StatusMultiStatus, status)
+		assert.True(t, response.Committed)
+		assert.Equal(t, 1, response.Succeeded)
+		assert.Equal(t, 2, response.Failed)
+		assert.Equal(t, BulkItemResult{Index: 0, SwiftCode: "PKOPPLPWXXX", Status: http.StatusCreated}, response.Results[0])
+		assert.Equal(t, http.StatusConflict, response.Results[1].Status)
+		assert.Equal(t, http.StatusBadRequest, response.Results[2].Status)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestBulkDeleteSwiftCodesHandler(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE").WithArgs("PKOPPLPW002").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE").WithArgs("PKOPPLPWXXX").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.POST("/bulk/delete", BulkDeleteSwiftCodesHandler(store.NewPostgresRepository(db)))
+
+	status, response := bulkRequest(t, router, "/bulk/delete", "application/json", `["PKOPPLPWXXX", {"swiftCode": "pkopplpw002"}]`)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, response.Committed)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Equal(t, "PKOPPLPW002", response.Results[1].SwiftCode)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
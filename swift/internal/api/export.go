@@ -0,0 +1,75 @@
+package api
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/export"
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/mbartnicki80/swift/internal/store"
+)
+
+func parseExportQuery(c *gin.Context) (store.ExportQuery, parser.Format, []FieldError) {
+	var errs []FieldError
+
+	format := parser.Format(strings.ToLower(c.DefaultQuery("format", string(parser.FormatCSV))))
+	switch format {
+	case parser.FormatCSV, parser.FormatExcel, parser.FormatNDJSON:
+	default:
+		errs = append(errs, FieldError{Field: "format", Message: "must be csv, xlsx or ndjson"})
+	}
+
+	var query store.ExportQuery
+	countryISO2 := c.Query("countryISO2")
+	if countryISO2 != "" && len(countryISO2) != 2 {
+		errs = append(errs, FieldError{Field: "countryISO2", Message: "must be a 2-letter code"})
+	}
+	filter, filterErrs := parseListFilter(c, countryISO2)
+	query.ListFilter = filter
+	errs = append(errs, filterErrs...)
+
+	sort, desc, sortErrs := parseSort(c)
+	query.Sort, query.Desc = sort, desc
+	errs = append(errs, sortErrs...)
+
+	return query, format, errs
+}
+
+func ExportSwiftCodesHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, format, errs := parseExportQuery(c)
+		if len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+
+		c.Header("Content-Type", export.ContentType(format))
+		c.Header("Content-Disposition", `attachment; filename="swift_codes.`+string(format)+`"`)
+
+		encoder, err := export.NewEncoder(format, c.Writer)
+		if err != nil {
+			respondProblem(c, internalProblem("Could not start export"))
+			return
+		}
+		defer encoder.Abort()
+		err = repo.Stream(c.Request.Context(), query, func(code model.SwiftCode) error {
+			return encoder.Encode(code)
+		})
+		if err == nil {
+			err = encoder.Close()
+		}
+		if err == nil {
+			return
+		}
+
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			respondProblem(c, storeProblem(err, "Could not export SWIFT codes"))
+			return
+		}
+		log.Printf("export aborted after response started: %v", err)
+		c.Abort()
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportSwiftCodesHandler(t *testing.T) {
+	t.Run("streams csv in the import layout", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DECLARE export_cursor").WithArgs("PL").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FETCH FORWARD").WillReturnRows(sqlmock.NewRows([]string{
+			"address", "bank_name", "country_iso2_code", "is_headquarter", "swift_code", "country_name",
+			"code_type", "town_name", "time_zone",
+		}).AddRow("PULAWSKA 15", "PKO BANK POLSKI", "PL", true, "PKOPPLPWXXX", "POLAND", "BIC11", "WARSZAWA", "Europe/Warsaw"))
+		mock.ExpectCommit()
+
+		router := gin.New()
+		router.GET("/export", ExportSwiftCodesHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export?format=csv&countryISO2=pl", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="swift_codes.csv"`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "COUNTRY ISO2 CODE,SWIFT CODE,CODE TYPE,NAME,ADDRESS,TOWN NAME,COUNTRY NAME,TIME ZONE\n"+
+			"PL,PKOPPLPWXXX,BIC11,PKO BANK POLSKI,PULAWSKA 15,WARSZAWA,POLAND,Europe/Warsaw\n", w.Body.String())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database failure before any output is a problem response", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+
+		router := gin.New()
+		router.GET("/export", ExportSwiftCodesHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export?format=ndjson", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("rejects unknown format", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		router := gin.New()
+		router.GET("/export", ExportSwiftCodesHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export?format=pdf", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -1,20 +1,60 @@
 package api
 
 import (
-	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/country"
 	"github.com/mbartnicki80/swift/internal/model"
 	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/validation"
 )
 
-func GetSwiftCodeHandler(db *sql.DB) gin.HandlerFunc {
+func wantsProvenance(c *gin.Context) bool {
+	include, _ := strconv.ParseBool(c.Query("provenance"))
+	return include
+}
+
+func attachProvenance(codes []model.SwiftCode, sources map[string]model.Provenance) {
+	for i := range codes {
+		if p, ok := sources[codes[i].SwiftCode]; ok {
+			codes[i].Provenance = &p
+		}
+	}
+}
+
+func swiftCodeResponse(code model.SwiftCode, branches []model.SwiftCode) gin.H {
+	response := gin.H{
+		"address":       code.Address,
+		"bankName":      code.BankName,
+		"codeType":      code.CodeType,
+		"countryISO2":   code.CountryISO2,
+		"countryName":   code.CountryName,
+		"isHeadquarter": code.IsHeadquarter,
+		"swiftCode":     code.SwiftCode,
+		"timeZone":      code.TimeZone,
+		"townName":      code.TownName,
+	}
+	if code.IsHeadquarter {
+		response["branches"] = branches
+	}
+	return response
+}
+
+func GetSwiftCodeHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		swiftCode := c.Param("swiftCode")
-		code, branches, err := store.FetchSwiftCode(db, swiftCode)
+		swiftCode := validation.NormalizeBIC(c.Param("swiftCode"))
+		code, branches, err := repo.Get(c.Request.Context(), swiftCode)
+		if errors.Is(err, store.ErrNotFound) {
+			respondProblem(c, notFoundProblem("SWIFT code "+swiftCode+" not found"))
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "SWIFT code not found"})
+			respondProblem(c, storeProblem(err, ""))
 			return
 		}
 
@@ -22,68 +62,255 @@ func GetSwiftCodeHandler(db *sql.DB) gin.HandlerFunc {
 			branches = []model.SwiftCode{}
 		}
 
-		response := gin.H{
-			"address":       code.Address,
-			"bankName":      code.BankName,
-			"countryISO2":   code.CountryISO2,
-			"countryName":   code.CountryName,
-			"isHeadquarter": code.IsHeadquarter,
-			"swiftCode":     code.SwiftCode,
+		var provenance *model.Provenance
+		if wantsProvenance(c) {
+			codes := []string{code.SwiftCode}
+			for _, branch := range branches {
+				codes = append(codes, branch.SwiftCode)
+			}
+			sources, err := repo.Provenance(c.Request.Context(), codes)
+			if err != nil {
+				respondProblem(c, storeProblem(err, ""))
+				return
+			}
+			if p, ok := sources[code.SwiftCode]; ok {
+				provenance = &p
+			}
+			attachProvenance(branches, sources)
 		}
-		if code.IsHeadquarter {
-			response["branches"] = branches
+
+		response := swiftCodeResponse(code, branches)
+		if wantsProvenance(c) {
+			response["provenance"] = provenance
 		}
 		c.JSON(http.StatusOK, response)
 	}
 }
 
-func GetSwiftCodesByCountryHandler(db *sql.DB) gin.HandlerFunc {
+func parseListFilter(c *gin.Context, countryISO2 string) (store.ListFilter, []FieldError) {
+	var errs []FieldError
+	filter := store.ListFilter{
+		CountryISO2:    strings.ToUpper(countryISO2),
+		BankNamePrefix: c.Query("bankName"),
+		TownName:       c.Query("townName"),
+	}
+	if raw := c.Query("isHeadquarter"); raw != "" {
+		isHeadquarter, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "isHeadquarter", Message: "must be true or false"})
+		}
+		filter.IsHeadquarter = &isHeadquarter
+	}
+	return filter, errs
+}
+
+func parseSort(c *gin.Context) (store.SortField, bool, []FieldError) {
+	raw := c.Query("sort")
+	if raw == "" {
+		return store.SortSwiftCode, false, nil
+	}
+	sort, ok := store.ParseSortField(strings.TrimPrefix(raw, "-"))
+	if !ok {
+		return sort, false, []FieldError{{Field: "sort", Message: "must be swiftCode or bankName, optionally prefixed with -"}}
+	}
+	return sort, strings.HasPrefix(raw, "-"), nil
+}
+
+func parseCountryQuery(c *gin.Context) (store.CountryQuery, []FieldError) {
+	query := store.CountryQuery{Limit: store.DefaultPageSize}
+
+	var errs []FieldError
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > store.MaxPageSize {
+			errs = append(errs, FieldError{Field: "limit", Message: fmt.Sprintf("must be an integer between 1 and %d", store.MaxPageSize)})
+		}
+		query.Limit = limit
+	}
+	sort, desc, sortErrs := parseSort(c)
+	query.Sort, query.Desc = sort, desc
+	errs = append(errs, sortErrs...)
+
+	filter, filterErrs := parseListFilter(c, c.Param("countryISO2"))
+	query.ListFilter = filter
+	errs = append(errs, filterErrs...)
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := store.DecodeCursor(raw)
+		if err == nil && (cursor.Sort != query.Sort || cursor.Desc != query.Desc) {
+			err = store.ErrInvalidCursor
+		}
+		if err != nil {
+			errs = append(errs, FieldError{Field: "cursor", Message: "is not valid for this listing"})
+		}
+		query.Cursor = &cursor
+	}
+	return query, errs
+}
+
+func pageLink(c *gin.Context, cursor *store.Cursor) *string {
+	if cursor == nil {
+		return nil
+	}
+	values := c.Request.URL.Query()
+	values.Set("cursor", cursor.Encode())
+	link := c.Request.URL.Path + "?" + values.Encode()
+	return &link
+}
+
+func GetSwiftCodesByCountryHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		iso2 := c.Param("countryISO2")
-		codes, err := store.FetchSwiftCodesByCountry(db, iso2)
+		query, errs := parseCountryQuery(c)
+		if len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+		filtered := query.IsHeadquarter != nil || query.BankNamePrefix != "" || query.TownName != ""
+
+		page, err := repo.ListByCountry(c.Request.Context(), query)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			respondProblem(c, storeProblem(err, ""))
 			return
 		}
-		if len(codes) == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "No SWIFT codes found"})
+		if page.Total == 0 && !filtered {
+			respondProblem(c, notFoundProblem("No SWIFT codes found for country "+query.CountryISO2))
 			return
 		}
 
+		codes := page.SwiftCodes
+		if wantsProvenance(c) && len(codes) > 0 {
+			swiftCodes := make([]string, len(codes))
+			for i, code := range codes {
+				swiftCodes[i] = code.SwiftCode
+			}
+			sources, err := repo.Provenance(c.Request.Context(), swiftCodes)
+			if err != nil {
+				respondProblem(c, storeProblem(err, ""))
+				return
+			}
+			attachProvenance(codes, sources)
+		}
+
+		countryName, _ := country.Name(query.CountryISO2)
+		if len(codes) > 0 {
+			countryName = codes[0].CountryName
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"countryISO2": iso2,
-			"countryName": codes[0].CountryName,
+			"countryISO2": query.CountryISO2,
+			"countryName": countryName,
 			"swiftCodes":  codes,
+			"total":       page.Total,
+			"links": gin.H{
+				"next": pageLink(c, page.Next),
+				"prev": pageLink(c, page.Prev),
+			},
 		})
 	}
 }
 
-func CreateSwiftCodeHandler(db *sql.DB) gin.HandlerFunc {
+func CreateSwiftCodeHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var record model.SwiftCode
 		if err := c.ShouldBindJSON(&record); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			respondProblem(c, bindingProblem(err))
+			return
+		}
+		if errs := validation.SwiftCode(&record); len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
 			return
 		}
 
-		err := store.InsertNewSwiftCode(db, record)
+		err := repo.Create(c.Request.Context(), record)
+		if errors.Is(err, store.ErrConflict) {
+			respondProblem(c, conflictProblem(ProblemConflict, "SWIFT code "+record.SwiftCode+" already exists"))
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not insert SWIFT code"})
+			respondProblem(c, storeProblem(err, "Could not insert SWIFT code"))
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "SWIFT code inserted successfully"})
+		c.Header("Location", "/v1/swift-codes/"+record.SwiftCode)
+		c.JSON(http.StatusCreated, gin.H{"message": "SWIFT code inserted successfully"})
 	}
 }
 
-func DeleteSwiftCodeHandler(db *sql.DB) gin.HandlerFunc {
+func DeleteSwiftCodeHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		swiftCode := c.Param("swiftCode")
-		err := store.DeleteSwiftCode(db, swiftCode)
+		swiftCode := validation.NormalizeBIC(c.Param("swiftCode"))
+		err := repo.Delete(c.Request.Context(), swiftCode)
+		if errors.Is(err, store.ErrNotFound) {
+			respondProblem(c, notFoundProblem("SWIFT code "+swiftCode+" not found"))
+			return
+		}
+		if errors.Is(err, store.ErrHasBranches) {
+			respondProblem(c, conflictProblem(ProblemHasBranches, "SWIFT code "+swiftCode+" has branches and cannot be deleted"))
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete SWIFT code"})
+			respondProblem(c, storeProblem(err, "Could not delete SWIFT code"))
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "SWIFT code deleted successfully"})
 	}
 }
+
+func UpdateSwiftCodeHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		swiftCode := validation.NormalizeBIC(c.Param("swiftCode"))
+		var record model.SwiftCode
+		if err := c.ShouldBindJSON(&record); err != nil {
+			respondProblem(c, bindingProblem(err))
+			return
+		}
+		if record.SwiftCode != "" && validation.NormalizeBIC(record.SwiftCode) != swiftCode {
+			respondProblem(c, validationProblem([]FieldError{{Field: "swiftCode", Message: "cannot be changed"}}))
+			return
+		}
+		record.SwiftCode = swiftCode
+		if errs := validation.SwiftCode(&record); len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+
+		err := repo.Update(c.Request.Context(), record)
+		if errors.Is(err, store.ErrNotFound) {
+			respondProblem(c, notFoundProblem("SWIFT code "+swiftCode+" not found"))
+			return
+		}
+		if err != nil {
+			respondProblem(c, storeProblem(err, "Could not update SWIFT code"))
+			return
+		}
+
+		c.JSON(http.StatusOK, record)
+	}
+}
+
+func PatchSwiftCodeHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		swiftCode := validation.NormalizeBIC(c.Param("swiftCode"))
+		var patch model.SwiftCodePatch
+		if err := c.ShouldBindJSON(&patch); err != nil {
+			respondProblem(c, bindingProblem(err))
+			return
+		}
+		if errs := validation.Patch(swiftCode, &patch); len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+
+		updated, err := repo.Patch(c.Request.Context(), swiftCode, patch)
+		if errors.Is(err, store.ErrNotFound) {
+			respondProblem(c, notFoundProblem("SWIFT code "+swiftCode+" not found"))
+			return
+		}
+		if err != nil {
+			respondProblem(c, storeProblem(err, "Could not update SWIFT code"))
+			return
+		}
+
+		c.JSON(http.StatusOK, updated)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/joho/godotenv"
 	"github.com/mbartnicki80/swift/internal/model"
 	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/store/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"net/http"
@@ -28,24 +30,39 @@ func clearTables(t *testing.T, db *sql.DB) {
 }
 
 func setupRouter(db *sql.DB) *gin.Engine {
+	return newRouter(store.NewPostgresRepository(db))
+}
+
+func newRouter(repo store.SwiftCodeRepository) *gin.Engine {
 	r := gin.Default()
 	v1 := r.Group("/v1")
 	{
 		swift := v1.Group("/swift-codes")
 		{
-			swift.GET("/:swiftCode", GetSwiftCodeHandler(db))
-			swift.GET("/country/:countryISO2", GetSwiftCodesByCountryHandler(db))
-			swift.POST("", CreateSwiftCodeHandler(db))
-			swift.DELETE("/:swiftCode", DeleteSwiftCodeHandler(db))
+			swift.GET("/search", SearchSwiftCodesHandler(repo))
+			swift.GET("/match", MatchBankNameHandler(repo))
+			swift.GET("/export", ExportSwiftCodesHandler(repo))
+			swift.GET("/:swiftCode", GetSwiftCodeHandler(repo))
+			swift.GET("/country/:countryISO2", GetSwiftCodesByCountryHandler(repo))
+			swift.POST("", CreateSwiftCodeHandler(repo))
+			swift.POST("/lookup", LookupSwiftCodesHandler(repo))
+			swift.POST("/bulk", BulkCreateSwiftCodesHandler(repo))
+			swift.POST("/bulk/delete", BulkDeleteSwiftCodesHandler(repo))
+			swift.PUT("/:swiftCode", UpdateSwiftCodeHandler(repo))
+			swift.PATCH("/:swiftCode", PatchSwiftCodeHandler(repo))
+			swift.DELETE("/:swiftCode", DeleteSwiftCodeHandler(repo))
 		}
 	}
 	return r
 }
 
-func setupTestDB(t *testing.T) *sql.DB {
-	err := godotenv.Load("../../.env")
-	if err != nil {
-		t.Fatalf("error loading .env")
+// setupTestRepository connects to the test database configured in
+// ../../.env and skips the test when there is none. The returned function
+// empties the tables.
+func setupTestRepository(t *testing.T) (store.SwiftCodeRepository, func()) {
+	godotenv.Load("../../.env")
+	if os.Getenv("TEST_DB_HOST") == "" {
+		t.Skip("TEST_DB_HOST is not set; the Postgres handler tests need the test database from ../../.env")
 	}
 
 	dbHost := os.Getenv("TEST_DB_HOST")
@@ -61,36 +78,66 @@ func setupTestDB(t *testing.T) *sql.DB {
 	if err != nil {
 		t.Fatalf("cannot connect to test DB: %v", err)
 	}
+	t.Cleanup(func() { db.Close() })
 
-	return db
+	return store.NewPostgresRepository(db), func() { clearTables(t, db) }
 }
 
 func TestCreateAndGetAndDeleteSwiftCode(t *testing.T) {
-	db := setupTestDB(t)
-	defer db.Close()
+	repo, reset := setupTestRepository(t)
+	testCreateAndGetAndDeleteSwiftCode(t, repo, reset)
+}
 
-	router := setupRouter(db)
+func testCreateAndGetAndDeleteSwiftCode(t *testing.T, repo store.SwiftCodeRepository, reset func()) {
+	router := newRouter(repo)
 
 	payload := `{
-		"swiftCode": "TESTCODE123",
+		"swiftCode": "TESTPLPWXXX",
 		"address": "TEST",
-		"countryName": "TEST",
-		"countryISO2": "TS",
+		"countryName": "Poland",
+		"countryISO2": "pl",
 		"isHeadquarter": true,
-		"bankName": "TEST"
+		"bankName": "TEST",
+		"townName": "TEST",
+		"timeZone": "Europe/Warsaw"
 	}`
 	req := httptest.NewRequest("POST", "/v1/swift-codes", strings.NewReader(payload))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d", w.Code)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
 	}
 
+	t.Run("Create Swift Code - Duplicate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/swift-codes", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Create Swift Code - Invalid", func(t *testing.T) {
+		invalid := `{"swiftCode": "ABC", "countryISO2": "PL", "bankName": "TEST", "isHeadquarter": true}`
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/swift-codes", strings.NewReader(invalid))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, ProblemValidation, response.Type)
+		require.NotEmpty(t, response.Errors)
+		assert.Equal(t, "swiftCode", response.Errors[0].Field)
+	})
+
 	t.Run("Get Swift Code - Found", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		req, _ := http.NewRequest(http.MethodGet, "/v1/swift-codes/TESTCODE123", nil)
+		req, _ := http.NewRequest(http.MethodGet, "/v1/swift-codes/TESTPLPWXXX", nil)
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusOK, w.Code)
@@ -99,9 +146,19 @@ func TestCreateAndGetAndDeleteSwiftCode(t *testing.T) {
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		require.NoError(t, err)
 
-		assert.Equal(t, "TESTCODE123", response["swiftCode"])
-		assert.Equal(t, "TS", response["countryISO2"])
-		assert.Equal(t, "TEST", response["countryName"])
+		assert.Equal(t, "TESTPLPWXXX", response["swiftCode"])
+		assert.Equal(t, "PL", response["countryISO2"])
+		assert.Equal(t, "POLAND", response["countryName"])
+		assert.Equal(t, "TEST", response["townName"])
+		assert.Equal(t, "Europe/Warsaw", response["timeZone"])
+	})
+
+	t.Run("Get Swift Code - Lowercase", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/v1/swift-codes/testplpwxxx", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
 	})
 
 	t.Run("Get Swift Code - Not Found", func(t *testing.T) {
@@ -111,35 +168,50 @@ func TestCreateAndGetAndDeleteSwiftCode(t *testing.T) {
 
 		assert.Equal(t, http.StatusNotFound, w.Code)
 
-		var response map[string]string
+		assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+		var response Problem
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		require.NoError(t, err)
-		assert.Equal(t, "SWIFT code not found", response["error"])
+		assert.Equal(t, ProblemNotFound, response.Type)
+		assert.Equal(t, http.StatusNotFound, response.Status)
+		assert.Equal(t, "SWIFT code INVALIDCODE not found", response.Detail)
+		assert.Equal(t, "/v1/swift-codes/INVALIDCODE", response.Instance)
 	})
 
-	req = httptest.NewRequest("DELETE", "/v1/swift-codes/TESTCODE123", nil)
+	req = httptest.NewRequest("DELETE", "/v1/swift-codes/testplpwxxx", nil)
 	w = httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected status 200 for DELETE, got %d", w.Code)
 	}
+
+	t.Run("Delete Swift Code - Not Found", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/v1/swift-codes/TESTPLPWXXX", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
 }
 
 func TestGetSwiftCodesByCountry(t *testing.T) {
-	db := setupTestDB(t)
-	defer db.Close()
+	repo, reset := setupTestRepository(t)
+	testGetSwiftCodesByCountry(t, repo, reset)
+}
 
-	router := setupRouter(db)
+func testGetSwiftCodesByCountry(t *testing.T, repo store.SwiftCodeRepository, reset func()) {
+	router := newRouter(repo)
 
 	deCode1 := model.SwiftCode{SwiftCode: "DEUTDEFFXXX", CountryISO2: "DE", BankName: "Deutsche Bank HQ", Address: "Frankfurt", CountryName: "Germany", IsHeadquarter: true}
 	deCode2 := model.SwiftCode{SwiftCode: "DEUTDEFF500", CountryISO2: "DE", BankName: "Deutsche Bank Branch", Address: "Berlin", CountryName: "Germany", IsHeadquarter: false}
 	frCode1 := model.SwiftCode{SwiftCode: "BNPAFRPPXXX", CountryISO2: "FR", BankName: "BNP Paribas", Address: "Paris", CountryName: "France", IsHeadquarter: true}
 
 	t.Run("Get Swift Codes By CountryISO2 - Found multiple", func(t *testing.T) {
-		clearTables(t, db)
-		require.NoError(t, store.InsertNewSwiftCode(db, deCode1))
-		require.NoError(t, store.InsertNewSwiftCode(db, deCode2))
+		reset()
+		require.NoError(t, repo.Create(t.Context(), deCode1))
+		require.NoError(t, repo.Create(t.Context(), deCode2))
 
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest(http.MethodGet, "/v1/swift-codes/country/DE", nil)
@@ -160,8 +232,8 @@ func TestGetSwiftCodesByCountry(t *testing.T) {
 	})
 
 	t.Run("Get Swift Codes By CountryISO2 - Found one", func(t *testing.T) {
-		clearTables(t, db)
-		require.NoError(t, store.InsertNewSwiftCode(db, frCode1))
+		reset()
+		require.NoError(t, repo.Create(t.Context(), frCode1))
 
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest(http.MethodGet, "/v1/swift-codes/country/FR", nil)
@@ -181,17 +253,172 @@ func TestGetSwiftCodesByCountry(t *testing.T) {
 		assert.Len(t, codes, 1, "Should find 1 code for FR")
 	})
 
+	t.Run("Get Swift Codes By CountryISO2 - Paginated", func(t *testing.T) {
+		reset()
+		require.NoError(t, repo.Create(t.Context(), deCode1))
+		require.NoError(t, repo.Create(t.Context(), deCode2))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/v1/swift-codes/country/DE?limit=1&sort=-bankName", nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Total      int               `json:"total"`
+			SwiftCodes []model.SwiftCode `json:"swiftCodes"`
+			Links      struct {
+				Next *string `json:"next"`
+				Prev *string `json:"prev"`
+			} `json:"links"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 2, response.Total)
+		require.Len(t, response.SwiftCodes, 1)
+		assert.Equal(t, deCode1.SwiftCode, response.SwiftCodes[0].SwiftCode)
+		assert.Nil(t, response.Links.Prev)
+		require.NotNil(t, response.Links.Next)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, *response.Links.Next, nil)
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.SwiftCodes, 1)
+		assert.Equal(t, deCode2.SwiftCode, response.SwiftCodes[0].SwiftCode)
+		assert.Nil(t, response.Links.Next)
+		assert.NotNil(t, response.Links.Prev)
+	})
+
 	t.Run("Swift Codes By Country - Not Found", func(t *testing.T) {
-		clearTables(t, db)
+		reset()
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest(http.MethodGet, "/v1/swift-codes/country/XY", nil)
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusNotFound, w.Code)
 
-		var response map[string]string
+		var response Problem
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		require.NoError(t, err)
-		assert.Equal(t, "No SWIFT codes found", response["error"])
+		assert.Equal(t, ProblemNotFound, response.Type)
+		assert.Equal(t, "No SWIFT codes found for country XY", response.Detail)
 	})
 }
+
+func TestUpdateAndPatchSwiftCode(t *testing.T) {
+	repo, reset := setupTestRepository(t)
+	testUpdateAndPatchSwiftCode(t, repo, reset)
+}
+
+func testUpdateAndPatchSwiftCode(t *testing.T, repo store.SwiftCodeRepository, reset func()) {
+	router := newRouter(repo)
+
+	hq := model.SwiftCode{SwiftCode: "DEUTDEFFXXX", CountryISO2: "DE", BankName: "Deutsche Bank HQ", Address: "Frankfurt", CountryName: "Germany", IsHeadquarter: true}
+	branch := model.SwiftCode{SwiftCode: "DEUTDEFF500", CountryISO2: "DE", BankName: "Deutsche Bank Branch", Address: "Berlin", CountryName: "Germany", IsHeadquarter: false}
+
+	reset()
+	require.NoError(t, repo.Create(t.Context(), hq))
+	require.NoError(t, repo.Create(t.Context(), branch))
+
+	t.Run("PUT replaces the record and keeps branches", func(t *testing.T) {
+		payload := `{
+			"address": "Taunusanlage 12",
+			"bankName": "Deutsche Bank AG",
+			"countryISO2": "DE",
+			"countryName": "Germany",
+			"isHeadquarter": true,
+			"townName": "FRANKFURT AM MAIN"
+		}`
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/v1/swift-codes/DEUTDEFFXXX", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/DEUTDEFFXXX", nil))
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "Taunusanlage 12", response["address"])
+		assert.Equal(t, "Deutsche Bank AG", response["bankName"])
+		assert.Len(t, response["branches"], 1)
+	})
+
+	t.Run("PATCH updates only given fields", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/v1/swift-codes/DEUTDEFF500", strings.NewReader(`{"address": "Unter den Linden 13"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "Unter den Linden 13", response["address"])
+		assert.Equal(t, "Deutsche Bank Branch", response["bankName"])
+	})
+
+	t.Run("PATCH rejects mismatched country", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/v1/swift-codes/DEUTDEFF500", strings.NewReader(`{"countryISO2": "FR"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("DELETE headquarter with branches", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/swift-codes/DEUTDEFFXXX", nil))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("PUT unknown code", func(t *testing.T) {
+		payload := `{"bankName": "X", "countryISO2": "DE", "countryName": "Germany", "isHeadquarter": true}`
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/v1/swift-codes/XXXXDEFFXXX", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// TestHandlersWithMemoryStore runs the database handler tests against the
+// in-memory store, so they also run where no test database is configured.
+func TestHandlersWithMemoryStore(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo store.SwiftCodeRepository, reset func())
+	}{
+		{"CreateAndGetAndDeleteSwiftCode", testCreateAndGetAndDeleteSwiftCode},
+		{"GetSwiftCodesByCountry", testGetSwiftCodesByCountry},
+		{"UpdateAndPatchSwiftCode", testUpdateAndPatchSwiftCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := memory.New()
+			tt.fn(t, repo, repo.Reset)
+		})
+	}
+}
+
+func TestGetSwiftCodesByCountryInvalidQuery(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	router := setupRouter(db)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/v1/swift-codes/country/DE?limit=0&sort=town&isHeadquarter=maybe&cursor=bogus", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	var fields []string
+	for _, fieldErr := range response.Errors {
+		fields = append(fields, fieldErr.Field)
+	}
+	assert.Equal(t, []string{"limit", "sort", "isHeadquarter", "cursor"}, fields)
+}
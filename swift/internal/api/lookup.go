@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/validation"
+)
+
+const MaxLookupCodes = 10000
+
+type lookupRequest struct {
+	SwiftCodes []string `json:"swiftCodes"`
+}
+
+func LookupSwiftCodesHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request lookupRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondProblem(c, bindingProblem(err))
+			return
+		}
+		if len(request.SwiftCodes) == 0 || len(request.SwiftCodes) > MaxLookupCodes {
+			respondProblem(c, validationProblem([]FieldError{{
+				Field:   "swiftCodes",
+				Message: fmt.Sprintf("must contain between 1 and %d codes", MaxLookupCodes),
+			}}))
+			return
+		}
+
+		codes := make([]string, len(request.SwiftCodes))
+		for i, code := range request.SwiftCodes {
+			codes[i] = validation.NormalizeBIC(code)
+		}
+
+		entries, notFound, err := repo.Lookup(c.Request.Context(), codes)
+		if err != nil {
+			respondProblem(c, storeProblem(err, "Could not look up SWIFT codes"))
+			return
+		}
+
+		found := make([]gin.H, len(entries))
+		for i, entry := range entries {
+			found[i] = swiftCodeResponse(entry.Code, entry.Branches)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"swiftCodes": found,
+			"notFound":   notFound,
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupSwiftCodesHandler(t *testing.T) {
+	t.Run("returns found codes and not found codes", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("ANY").
+			WillReturnRows(sqlmock.NewRows([]string{
+				"swift_code", "address", "country_name", "is_headquarter", "country_iso2_code", "bank_name",
+				"code_type", "town_name", "time_zone", "headquarter",
+			}).AddRow("PKOPPLPW002", "Krakow", "POLAND", false, "PL", "PKO", "BIC11", "KRAKOW", "Europe/Warsaw", "PKOPPLPWXXX").
+				AddRow("PKOPPLPWXXX", "Warsaw", "POLAND", true, "PL", "PKO", "BIC11", "WARSZAWA", "Europe/Warsaw", nil))
+
+		router := gin.New()
+		router.POST("/v1/swift-codes/lookup", LookupSwiftCodesHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/swift-codes/lookup", strings.NewReader(`{"swiftCodes": ["pkopplpwxxx", "NOPENOPEXXX"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			SwiftCodes []map[string]interface{} `json:"swiftCodes"`
+			NotFound   []string                 `json:"notFound"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []string{"NOPENOPEXXX"}, response.NotFound)
+		require.Len(t, response.SwiftCodes, 1)
+		assert.Equal(t, "PKOPPLPWXXX", response.SwiftCodes[0]["swiftCode"])
+		assert.Len(t, response.SwiftCodes[0]["branches"], 1)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects an empty list", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		router := gin.New()
+		router.POST("/v1/swift-codes/lookup", LookupSwiftCodesHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/swift-codes/lookup", strings.NewReader(`{"swiftCodes": []}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
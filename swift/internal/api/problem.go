@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/validation"
+)
+
+const problemContentType = "application/problem+json"
+
+const (
+	ProblemBadRequest    = "/problems/bad-request"
+	ProblemValidation    = "/problems/validation-error"
+	ProblemNotFound      = "/problems/not-found"
+	ProblemConflict      = "/problems/conflict"
+	ProblemHasBranches   = "/problems/has-branches"
+	ProblemUnprocessable = "/problems/unprocessable-import"
+	ProblemRolledBack    = "/problems/rolled-back"
+	ProblemInternal      = "/problems/internal-error"
+	ProblemTimeout       = "/problems/timeout"
+	ProblemClientClosed  = "/problems/client-closed-request"
+)
+
+// StatusClientClosedRequest is the non-standard status nginx logs when the
+// client goes away before the response is ready. Nobody reads the body, but
+// it keeps cancelled requests apart from server errors in access logs.
+const StatusClientClosedRequest = 499
+
+type FieldError = validation.FieldError
+
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+func NewProblem(status int, problemType, title, detail string) *Problem {
+	return &Problem{
+		Type:   problemType,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func badRequestProblem(detail string) *Problem {
+	return NewProblem(http.StatusBadRequest, ProblemBadRequest, "Bad request", detail)
+}
+
+func validationProblem(errs []FieldError) *Problem {
+	p := NewProblem(http.StatusBadRequest, ProblemValidation, "Validation failed", "One or more fields are invalid")
+	p.Errors = errs
+	return p
+}
+
+func notFoundProblem(detail string) *Problem {
+	return NewProblem(http.StatusNotFound, ProblemNotFound, "Resource not found", detail)
+}
+
+func conflictProblem(problemType, detail string) *Problem {
+	return NewProblem(http.StatusConflict, problemType, "Conflict", detail)
+}
+
+func internalProblem(detail string) *Problem {
+	return NewProblem(http.StatusInternalServerError, ProblemInternal, "Internal server error", detail)
+}
+
+// storeProblem maps a failed repository call. Queries cut short by the query
+// timeout or by the client disconnecting are not internal errors.
+func storeProblem(err error, detail string) *Problem {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewProblem(http.StatusGatewayTimeout, ProblemTimeout, "Query timed out", "The database did not answer in time")
+	case errors.Is(err, context.Canceled):
+		return NewProblem(StatusClientClosedRequest, ProblemClientClosed, "Client closed request", "")
+	default:
+		return internalProblem(detail)
+	}
+}
+
+func bindingProblem(err error) *Problem {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return validationProblem([]FieldError{{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+		}})
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return badRequestProblem(fmt.Sprintf("Malformed JSON at offset %d", syntaxErr.Offset))
+	}
+	return badRequestProblem("Request body is not valid JSON")
+}
+
+func respondProblem(c *gin.Context, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondProblem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/v1/swift-codes/:swiftCode", func(c *gin.Context) {
+		respondProblem(c, validationProblem([]FieldError{{Field: "swiftCode", Message: "must be 8 or 11 characters"}}))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/ABC", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+	var problem Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, ProblemValidation, problem.Type)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "/v1/swift-codes/ABC", problem.Instance)
+	assert.Equal(t, []FieldError{{Field: "swiftCode", Message: "must be 8 or 11 characters"}}, problem.Errors)
+}
+
+func TestBindingProblem(t *testing.T) {
+	var body struct {
+		IsHeadquarter bool `json:"isHeadquarter"`
+	}
+
+	err := json.NewDecoder(strings.NewReader(`{"isHeadquarter": "yes"}`)).Decode(&body)
+	problem := bindingProblem(err)
+	assert.Equal(t, ProblemValidation, problem.Type)
+	assert.Equal(t, "isHeadquarter", problem.Errors[0].Field)
+
+	err = json.NewDecoder(strings.NewReader(`{"isHeadquarter": `)).Decode(&body)
+	problem = bindingProblem(err)
+	assert.Equal(t, ProblemBadRequest, problem.Type)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+}
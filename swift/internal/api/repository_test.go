@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/store/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository implements the methods the handler tests need; calling any
+// other method panics through the nil embedded interface. A non-nil err is
+// returned by Get instead of a result.
+type fakeRepository struct {
+	store.SwiftCodeRepository
+	codes   map[string]model.SwiftCode
+	created []model.SwiftCode
+	err     error
+}
+
+func newFakeRepository(codes ...model.SwiftCode) *fakeRepository {
+	repo := &fakeRepository{codes: make(map[string]model.SwiftCode)}
+	for _, code := range codes {
+		repo.codes[code.SwiftCode] = code
+	}
+	return repo
+}
+
+func (r *fakeRepository) branches(swiftCode string) []model.SwiftCode {
+	branches := []model.SwiftCode{}
+	for _, code := range r.codes {
+		if !code.IsHeadquarter && code.SwiftCode[:8] == swiftCode[:8] {
+			branches = append(branches, code)
+		}
+	}
+	return branches
+}
+
+func (r *fakeRepository) Get(ctx context.Context, swiftCode string) (model.SwiftCode, []model.SwiftCode, error) {
+	if r.err != nil {
+		return model.SwiftCode{}, nil, r.err
+	}
+	code, ok := r.codes[swiftCode]
+	if !ok {
+		return model.SwiftCode{}, nil, store.ErrNotFound
+	}
+	if !code.IsHeadquarter {
+		return code, nil, nil
+	}
+	return code, r.branches(swiftCode), nil
+}
+
+func (r *fakeRepository) Create(ctx context.Context, code model.SwiftCode) error {
+	if _, ok := r.codes[code.SwiftCode]; ok {
+		return store.ErrConflict
+	}
+	r.codes[code.SwiftCode] = code
+	r.created = append(r.created, code)
+	return nil
+}
+
+func (r *fakeRepository) Patch(ctx context.Context, swiftCode string, patch model.SwiftCodePatch) (model.SwiftCode, error) {
+	code, ok := r.codes[swiftCode]
+	if !ok {
+		return model.SwiftCode{}, store.ErrNotFound
+	}
+	code = patch.Apply(code)
+	r.codes[swiftCode] = code
+	return code, nil
+}
+
+func (r *fakeRepository) Delete(ctx context.Context, swiftCode string) error {
+	code, ok := r.codes[swiftCode]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if code.IsHeadquarter && len(r.branches(swiftCode)) > 0 {
+		return store.ErrHasBranches
+	}
+	delete(r.codes, swiftCode)
+	return nil
+}
+
+func TestHandlersWithRepository(t *testing.T) {
+	hq := model.SwiftCode{SwiftCode: "PKOPPLPWXXX", CountryISO2: "PL", CountryName: "POLAND", BankName: "PKO", IsHeadquarter: true}
+	branch := model.SwiftCode{SwiftCode: "PKOPPLPW002", CountryISO2: "PL", CountryName: "POLAND", BankName: "PKO"}
+
+	t.Run("GET headquarter includes branches", func(t *testing.T) {
+		router := newRouter(newFakeRepository(hq, branch))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/PKOPPLPWXXX", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			SwiftCode string            `json:"swiftCode"`
+			Branches  []model.SwiftCode `json:"branches"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, hq.SwiftCode, response.SwiftCode)
+		require.Len(t, response.Branches, 1)
+		assert.Equal(t, branch.SwiftCode, response.Branches[0].SwiftCode)
+	})
+
+	t.Run("GET unknown code", func(t *testing.T) {
+		router := newRouter(newFakeRepository())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/PKOPPLPWXXX", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("GET that times out", func(t *testing.T) {
+		repo := newFakeRepository(hq)
+		repo.err = fmt.Errorf("%w: pq: canceling statement due to user request", context.DeadlineExceeded)
+		router := newRouter(repo)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/PKOPPLPWXXX", nil))
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, ProblemTimeout, problem.Type)
+	})
+
+	t.Run("GET from a client that went away", func(t *testing.T) {
+		router := newRouter(memory.New())
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/PKOPPLPWXXX", nil).WithContext(ctx))
+		assert.Equal(t, StatusClientClosedRequest, w.Code)
+	})
+
+	t.Run("POST stores the normalized code", func(t *testing.T) {
+		repo := newFakeRepository(hq)
+		router := newRouter(repo)
+
+		payload := `{"swiftCode": "pkopplpw003", "countryISO2": "pl", "bankName": "PKO"}`
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/swift-codes", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "/v1/swift-codes/PKOPPLPW003", w.Header().Get("Location"))
+		require.Len(t, repo.created, 1)
+		assert.Equal(t, "POLAND", repo.created[0].CountryName)
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/v1/swift-codes", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("PATCH returns the updated code", func(t *testing.T) {
+		router := newRouter(newFakeRepository(hq, branch))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/v1/swift-codes/PKOPPLPW002", strings.NewReader(`{"townName": "KRAKOW"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var response model.SwiftCode
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "KRAKOW", response.TownName)
+	})
+
+	t.Run("DELETE headquarter with branches", func(t *testing.T) {
+		router := newRouter(newFakeRepository(hq, branch))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/swift-codes/PKOPPLPWXXX", nil))
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, ProblemHasBranches, problem.Type)
+	})
+}
+
+func TestCreateGetAndDeleteWithMemoryStore(t *testing.T) {
+	router := newRouter(memory.New())
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/v1/swift-codes", `{"swiftCode": "PKOPPLPW002", "countryISO2": "PL", "bankName": "PKO"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = send(http.MethodPost, "/v1/swift-codes", `{"swiftCode": "PKOPPLPW", "countryISO2": "PL", "bankName": "PKO", "isHeadquarter": true}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = send(http.MethodGet, "/v1/swift-codes/PKOPPLPWXXX", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Branches []model.SwiftCode `json:"branches"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Branches, 1)
+	assert.Equal(t, "PKOPPLPW002", response.Branches[0].SwiftCode)
+
+	w = send(http.MethodGet, "/v1/swift-codes/country/PL", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":2`)
+
+	assert.Equal(t, http.StatusConflict, send(http.MethodDelete, "/v1/swift-codes/PKOPPLPWXXX", "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/v1/swift-codes/PKOPPLPW002", "").Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/v1/swift-codes/PKOPPLPWXXX", "").Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/v1/swift-codes/PKOPPLPWXXX", "").Code)
+}
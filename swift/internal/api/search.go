@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/store"
+)
+
+func parseSearchQuery(c *gin.Context) (store.SearchQuery, []FieldError) {
+	var errs []FieldError
+	query := store.SearchQuery{
+		Text:        c.Query("q"),
+		CountryISO2: strings.ToUpper(c.Query("countryISO2")),
+		TownName:    c.Query("townName"),
+		Limit:       store.DefaultSearchLimit,
+	}
+
+	if store.SearchTerms(query.Text) == "" {
+		errs = append(errs, FieldError{Field: "q", Message: "must contain at least one word"})
+	}
+	if query.CountryISO2 != "" && len(query.CountryISO2) != 2 {
+		errs = append(errs, FieldError{Field: "countryISO2", Message: "must be a 2-letter code"})
+	}
+	if raw := c.Query("isHeadquarter"); raw != "" {
+		isHeadquarter, err := strconv.ParseBool(raw)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "isHeadquarter", Message: "must be true or false"})
+		}
+		query.IsHeadquarter = &isHeadquarter
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > store.MaxSearchLimit {
+			errs = append(errs, FieldError{Field: "limit", Message: fmt.Sprintf("must be an integer between 1 and %d", store.MaxSearchLimit)})
+		}
+		query.Limit = limit
+	}
+	return query, errs
+}
+
+func SearchSwiftCodesHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, errs := parseSearchQuery(c)
+		if len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+
+		results, err := repo.Search(c.Request.Context(), query)
+		if err != nil {
+			respondProblem(c, storeProblem(err, "Could not search SWIFT codes"))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"query":   query.Text,
+			"count":   len(results),
+			"results": results,
+		})
+	}
+}
+
+func parseMatchQuery(c *gin.Context) (store.MatchQuery, []FieldError) {
+	var errs []FieldError
+	query := store.MatchQuery{
+		Name:        strings.TrimSpace(c.Query("name")),
+		CountryISO2: strings.ToUpper(c.Query("countryISO2")),
+		Threshold:   store.DefaultMatchThreshold,
+		Limit:       store.DefaultMatchLimit,
+	}
+
+	if query.Name == "" {
+		errs = append(errs, FieldError{Field: "name", Message: "is required"})
+	}
+	if query.CountryISO2 != "" && len(query.CountryISO2) != 2 {
+		errs = append(errs, FieldError{Field: "countryISO2", Message: "must be a 2-letter code"})
+	}
+	if raw := c.Query("minScore"); raw != "" {
+		threshold, err := strconv.ParseFloat(raw, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			errs = append(errs, FieldError{Field: "minScore", Message: "must be a number greater than 0 and at most 1"})
+		}
+		query.Threshold = threshold
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > store.MaxMatchLimit {
+			errs = append(errs, FieldError{Field: "limit", Message: fmt.Sprintf("must be an integer between 1 and %d", store.MaxMatchLimit)})
+		}
+		query.Limit = limit
+	}
+	return query, errs
+}
+
+func MatchBankNameHandler(repo store.SwiftCodeRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query, errs := parseMatchQuery(c)
+		if len(errs) > 0 {
+			respondProblem(c, validationProblem(errs))
+			return
+		}
+
+		candidates, err := repo.MatchBankName(c.Request.Context(), query)
+		if err != nil {
+			respondProblem(c, storeProblem(err, "Could not match bank name"))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"name":       query.Name,
+			"minScore":   query.Threshold,
+			"candidates": candidates,
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchSwiftCodesHandler(t *testing.T) {
+	t.Run("returns ranked results", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery("to_tsquery").
+			WithArgs("deutsche:* & bank:* & hamburg:*", "DE", true, 5).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"address", "bank_name", "country_iso2_code", "is_headquarter", "swift_code", "country_name",
+				"code_type", "town_name", "time_zone", "rank",
+			}).AddRow("ALSTERTOR 1", "DEUTSCHE BANK AG", "DE", true, "DEUTDEHHXXX", "GERMANY", "BIC11", "HAMBURG", "Europe/Berlin", 0.6))
+
+		router := gin.New()
+		router.GET("/v1/swift-codes/search", SearchSwiftCodesHandler(store.NewPostgresRepository(db)))
+		router.GET("/v1/swift-codes/:swiftCode", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/search?q=Deutsche+Bank,+Hamburg&countryISO2=de&isHeadquarter=true&limit=5", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Count   int `json:"count"`
+			Results []struct {
+				SwiftCode string  `json:"swiftCode"`
+				TownName  string  `json:"townName"`
+				Rank      float64 `json:"rank"`
+			} `json:"results"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 1, response.Count)
+		assert.Equal(t, "DEUTDEHHXXX", response.Results[0].SwiftCode)
+		assert.Equal(t, "HAMBURG", response.Results[0].TownName)
+		assert.Equal(t, 0.6, response.Results[0].Rank)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("requires a query", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		router := gin.New()
+		router.GET("/v1/swift-codes/search", SearchSwiftCodesHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/search?q=---&limit=1000", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		require.Len(t, problem.Errors, 2)
+		assert.Equal(t, "q", problem.Errors[0].Field)
+		assert.Equal(t, "limit", problem.Errors[1].Field)
+	})
+}
+
+func TestMatchBankNameHandler(t *testing.T) {
+	t.Run("returns scored candidates", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("set_config").WithArgs("0.5").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("similarity").
+			WithArgs("DEUTSCHE BK AG", "DE", 10).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"address", "bank_name", "country_iso2_code", "is_headquarter", "swift_code", "country_name",
+				"code_type", "town_name", "time_zone", "score",
+			}).AddRow("TAUNUSANLAGE 12", "DEUTSCHE BANK AG", "DE", true, "DEUTDEFFXXX", "GERMANY", "BIC11", "FRANKFURT AM MAIN", "Europe/Berlin", 0.65))
+		mock.ExpectCommit()
+
+		router := gin.New()
+		router.GET("/v1/swift-codes/match", MatchBankNameHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/match?name=DEUTSCHE+BK+AG&countryISO2=de&minScore=0.5", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Candidates []struct {
+				SwiftCode string  `json:"swiftCode"`
+				Score     float64 `json:"score"`
+			} `json:"candidates"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Candidates, 1)
+		assert.Equal(t, "DEUTDEFFXXX", response.Candidates[0].SwiftCode)
+		assert.Equal(t, 0.65, response.Candidates[0].Score)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("validates parameters", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		router := gin.New()
+		router.GET("/v1/swift-codes/match", MatchBankNameHandler(store.NewPostgresRepository(db)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/swift-codes/match?minScore=2", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		require.Len(t, problem.Errors, 2)
+		assert.Equal(t, "name", problem.Errors[0].Field)
+		assert.Equal(t, "minScore", problem.Errors[1].Field)
+	})
+}
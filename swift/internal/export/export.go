@@ -0,0 +1,185 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/xuri/excelize/v2"
+)
+
+// Layout is the column order of the original swift_codes.xlsx, which the
+// parser reads back, so an export can be imported again unchanged.
+var Layout = []parser.Column{
+	parser.ColumnISO2Code,
+	parser.ColumnSwiftCode,
+	parser.ColumnCodeType,
+	parser.ColumnBankName,
+	parser.ColumnAddress,
+	parser.ColumnTownName,
+	parser.ColumnCountry,
+	parser.ColumnTimeZone,
+}
+
+const SheetName = "SWIFT codes"
+
+// Encoder writes codes in one export format. Close finishes the output;
+// Abort releases what the encoder holds without finishing it and does nothing
+// after Close, so callers can defer it.
+type Encoder interface {
+	Encode(code model.SwiftCode) error
+	Close() error
+	Abort()
+}
+
+func header() []string {
+	names := make([]string, len(Layout))
+	for i, column := range Layout {
+		names[i] = string(column)
+	}
+	return names
+}
+
+func row(code model.SwiftCode) []string {
+	return []string{
+		code.CountryISO2,
+		code.SwiftCode,
+		code.CodeType,
+		code.BankName,
+		code.Address,
+		code.TownName,
+		code.CountryName,
+		code.TimeZone,
+	}
+}
+
+func ContentType(format parser.Format) string {
+	switch format {
+	case parser.FormatCSV:
+		return "text/csv; charset=utf-8"
+	case parser.FormatExcel:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+func NewEncoder(format parser.Format, w io.Writer) (Encoder, error) {
+	switch parser.Format(strings.ToLower(string(format))) {
+	case parser.FormatCSV:
+		return newCSVEncoder(w)
+	case parser.FormatExcel:
+		return newExcelEncoder(w)
+	case parser.FormatNDJSON:
+		return ndjsonEncoder{json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer) (*csvEncoder, error) {
+	e := &csvEncoder{w: csv.NewWriter(w)}
+	return e, e.w.Write(header())
+}
+
+func (e *csvEncoder) Encode(code model.SwiftCode) error {
+	return e.w.Write(row(code))
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) Abort() {}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e ndjsonEncoder) Encode(code model.SwiftCode) error {
+	return e.enc.Encode(code)
+}
+
+func (e ndjsonEncoder) Close() error {
+	return nil
+}
+
+func (e ndjsonEncoder) Abort() {}
+
+// excelEncoder writes rows through excelize's stream writer, which spills to
+// a temporary file instead of building the sheet in memory. The workbook is
+// written out on Close, as XLSX is a zip archive that cannot be emitted
+// incrementally.
+type excelEncoder struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+	closed bool
+}
+
+func cells(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func newExcelEncoder(w io.Writer) (*excelEncoder, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName(file.GetSheetName(0), SheetName); err != nil {
+		file.Close()
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(SheetName)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	e := &excelEncoder{w: w, file: file, stream: stream, row: 1}
+	if err := e.writeRow(header()); err != nil {
+		e.Abort()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *excelEncoder) writeRow(values []string) error {
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	e.row++
+	return e.stream.SetRow(cell, cells(values))
+}
+
+func (e *excelEncoder) Encode(code model.SwiftCode) error {
+	return e.writeRow(row(code))
+}
+
+func (e *excelEncoder) Close() error {
+	defer e.Abort()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
+
+// Abort removes the stream writer's temporary files.
+func (e *excelEncoder) Abort() {
+	if e.closed {
+		return
+	}
+	e.closed = true
+	e.file.Close()
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var codes = []model.SwiftCode{
+	{SwiftCode: "PKOPPLPWXXX", CountryISO2: "PL", CodeType: "BIC11", BankName: "PKO BANK POLSKI", Address: "PULAWSKA 15, WARSZAWA", TownName: "WARSZAWA", CountryName: "POLAND", TimeZone: "Europe/Warsaw", IsHeadquarter: true},
+	{SwiftCode: "PKOPPLPW002", CountryISO2: "PL", CodeType: "BIC11", BankName: "PKO BANK POLSKI", Address: "\"RYNEK\" 1", TownName: "KRAKOW", CountryName: "POLAND", TimeZone: "Europe/Warsaw"},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []parser.Format{parser.FormatCSV, parser.FormatExcel, parser.FormatNDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			encoder, err := NewEncoder(format, &buf)
+			require.NoError(t, err)
+			for _, code := range codes {
+				require.NoError(t, encoder.Encode(code))
+			}
+			require.NoError(t, encoder.Close())
+
+			decoder, err := parser.NewDecoder(format, parser.Options{})
+			require.NoError(t, err)
+			records, err := parser.DecodeAll(decoder, &buf)
+			require.NoError(t, err)
+
+			require.Len(t, records, len(codes))
+			for i, record := range records {
+				assert.Equal(t, codes[i].SwiftCode, record.SwiftCode)
+				assert.Equal(t, codes[i].CountryISO2, record.ISO2Code)
+				assert.Equal(t, codes[i].CodeType, record.CodeType)
+				assert.Equal(t, codes[i].BankName, record.BankName)
+				assert.Equal(t, codes[i].Address, record.Address)
+				assert.Equal(t, codes[i].TownName, record.TownName)
+				assert.Equal(t, codes[i].CountryName, record.Country)
+				assert.Equal(t, codes[i].TimeZone, record.TimeZone)
+				assert.Equal(t, codes[i].IsHeadquarter, record.IsHeadquarter)
+			}
+		})
+	}
+}
+
+func TestNewEncoderUnsupportedFormat(t *testing.T) {
+	_, err := NewEncoder(parser.FormatBICDirectory, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestExcelAbortRemovesTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	encoder, err := NewEncoder(parser.FormatExcel, &bytes.Buffer{})
+	require.NoError(t, err)
+	code := codes[0]
+	code.BankName = strings.Repeat("PKO BANK POLSKI ", 50)
+	for i := 0; i < 20000; i++ {
+		require.NoError(t, encoder.Encode(code))
+	}
+	spilled, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, spilled, "the stream writer spilled to a temporary file")
+
+	encoder.Abort()
+	left, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, left)
+	encoder.Abort()
+}
@@ -1,10 +1,90 @@
 package model
 
+import "time"
+
 type SwiftCode struct {
-	Address       string `json:"address"`
-	BankName      string `json:"bankName"`
-	CountryISO2   string `json:"countryISO2"`
-	CountryName   string `json:"countryName"`
-	IsHeadquarter bool   `json:"isHeadquarter"`
-	SwiftCode     string `json:"swiftCode"`
+	Address       string      `json:"address"`
+	BankName      string      `json:"bankName"`
+	CodeType      string      `json:"codeType"`
+	CountryISO2   string      `json:"countryISO2"`
+	CountryName   string      `json:"countryName"`
+	IsHeadquarter bool        `json:"isHeadquarter"`
+	SwiftCode     string      `json:"swiftCode"`
+	TimeZone      string      `json:"timeZone"`
+	TownName      string      `json:"townName"`
+	Provenance    *Provenance `json:"provenance,omitempty"`
+}
+
+type Import struct {
+	ID             int64      `json:"id"`
+	SourceFilename string     `json:"sourceFilename"`
+	Checksum       string     `json:"checksum"`
+	Format         string     `json:"format"`
+	Mode           string     `json:"mode"`
+	Operator       string     `json:"operator"`
+	Status         string     `json:"status"`
+	TotalRows      int        `json:"totalRows"`
+	AcceptedRows   int        `json:"acceptedRows"`
+	RejectedRows   int        `json:"rejectedRows"`
+	StartedAt      time.Time  `json:"startedAt"`
+	FinishedAt     *time.Time `json:"finishedAt"`
+}
+
+type Provenance struct {
+	ImportID       int64      `json:"importId"`
+	SourceFilename string     `json:"sourceFilename"`
+	Checksum       string     `json:"checksum"`
+	Operator       string     `json:"operator"`
+	ImportedAt     time.Time  `json:"importedAt"`
+	FinishedAt     *time.Time `json:"finishedAt"`
+}
+
+type SwiftCodePatch struct {
+	Address       *string `json:"address"`
+	BankName      *string `json:"bankName"`
+	CodeType      *string `json:"codeType"`
+	CountryISO2   *string `json:"countryISO2"`
+	CountryName   *string `json:"countryName"`
+	IsHeadquarter *bool   `json:"isHeadquarter"`
+	SwiftCode     *string `json:"swiftCode"`
+	TimeZone      *string `json:"timeZone"`
+	TownName      *string `json:"townName"`
+}
+
+func (p SwiftCodePatch) Apply(code SwiftCode) SwiftCode {
+	if p.Address != nil {
+		code.Address = *p.Address
+	}
+	if p.BankName != nil {
+		code.BankName = *p.BankName
+	}
+	if p.CodeType != nil {
+		code.CodeType = *p.CodeType
+	}
+	if p.CountryISO2 != nil {
+		code.CountryISO2 = *p.CountryISO2
+	}
+	if p.CountryName != nil {
+		code.CountryName = *p.CountryName
+	}
+	if p.IsHeadquarter != nil {
+		code.IsHeadquarter = *p.IsHeadquarter
+	}
+	if p.TimeZone != nil {
+		code.TimeZone = *p.TimeZone
+	}
+	if p.TownName != nil {
+		code.TownName = *p.TownName
+	}
+	return code
+}
+
+type SearchResult struct {
+	SwiftCode
+	Rank float64 `json:"rank"`
+}
+
+type MatchCandidate struct {
+	SwiftCode
+	Score float64 `json:"score"`
 }
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mbartnicki80/swift/internal/country"
+)
+
+var bicDirectoryRequired = []Column{ColumnSwiftCode, ColumnISO2Code, ColumnBankName}
+
+var bicDirectoryOptional = []Column{
+	ColumnBranchCode, ColumnBranchInfo, ColumnAddress, ColumnTownName, ColumnZipCode,
+	ColumnCountry, ColumnModificationFlag, ColumnEffectiveDate, ColumnModificationDate,
+}
+
+var bicDirectoryDateLayouts = []string{"20060102", "2006-01-02", "02.01.2006"}
+
+type BICDirectoryDecoder struct {
+	Aliases map[Column][]string
+	Comma   rune
+}
+
+func parseDirectoryDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range bicDirectoryDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func (d BICDirectoryDecoder) Decode(r io.Reader, fn func(SwiftRecord) error) error {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	if d.Comma != 0 {
+		reader.Comma = d.Comma
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return errors.New("BIC directory file has no header row")
+	}
+	if err != nil {
+		return err
+	}
+
+	columns, err := mapColumns(header, bicDirectoryRequired, bicDirectoryOptional, d.Aliases)
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+
+		swiftCode := columns.Get(row, ColumnSwiftCode)
+		if len(swiftCode) == 8 {
+			branch := columns.Get(row, ColumnBranchCode)
+			if branch == "" {
+				branch = "XXX"
+			}
+			swiftCode += branch
+		}
+
+		iso2 := columns.Get(row, ColumnISO2Code)
+		countryName := columns.Get(row, ColumnCountry)
+		if countryName == "" {
+			countryName, _ = country.Name(iso2)
+		}
+
+		codeType := "BIC11"
+		if len(swiftCode) != 11 {
+			codeType = ""
+		}
+
+		var invalid string
+		effective, err := parseDirectoryDate(columns.Get(row, ColumnEffectiveDate))
+		if err != nil {
+			invalid = "effective date: " + err.Error()
+		}
+		modified, err := parseDirectoryDate(columns.Get(row, ColumnModificationDate))
+		if err != nil && invalid == "" {
+			invalid = "modification date: " + err.Error()
+		}
+
+		err = fn(SwiftRecord{
+			Row:              line,
+			SwiftCode:        swiftCode,
+			ISO2Code:         iso2,
+			CodeType:         codeType,
+			BankName:         columns.Get(row, ColumnBankName),
+			Address:          columns.Get(row, ColumnAddress),
+			TownName:         columns.Get(row, ColumnTownName),
+			Country:          countryName,
+			IsHeadquarter:    strings.HasSuffix(swiftCode, "XXX"),
+			BranchInfo:       columns.Get(row, ColumnBranchInfo),
+			ZipCode:          columns.Get(row, ColumnZipCode),
+			ModificationFlag: strings.ToUpper(columns.Get(row, ColumnModificationFlag)),
+			EffectiveDate:    effective,
+			ModificationDate: modified,
+			invalid:          invalid,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
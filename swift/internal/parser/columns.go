@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Column string
+
+const (
+	ColumnISO2Code  Column = "COUNTRY ISO2 CODE"
+	ColumnSwiftCode Column = "SWIFT CODE"
+	ColumnCodeType  Column = "CODE TYPE"
+	ColumnBankName  Column = "NAME"
+	ColumnAddress   Column = "ADDRESS"
+	ColumnTownName  Column = "TOWN NAME"
+	ColumnCountry   Column = "COUNTRY NAME"
+	ColumnTimeZone  Column = "TIME ZONE"
+
+	ColumnBranchCode       Column = "BRANCH CODE"
+	ColumnBranchInfo       Column = "BRANCH INFORMATION"
+	ColumnZipCode          Column = "ZIP CODE"
+	ColumnModificationFlag Column = "MODIFICATION FLAG"
+	ColumnEffectiveDate    Column = "EFFECTIVE DATE"
+	ColumnModificationDate Column = "MODIFICATION DATE"
+)
+
+var RequiredColumns = []Column{ColumnISO2Code, ColumnSwiftCode, ColumnBankName, ColumnCountry}
+
+var OptionalColumns = []Column{ColumnCodeType, ColumnAddress, ColumnTownName, ColumnTimeZone}
+
+var DefaultAliases = map[Column][]string{
+	ColumnISO2Code:  {"COUNTRY ISO2", "ISO2", "COUNTRY CODE"},
+	ColumnSwiftCode: {"SWIFT", "BIC", "SWIFT BIC", "BIC CODE"},
+	ColumnCodeType:  {"TYPE"},
+	ColumnBankName:  {"BANK NAME", "INSTITUTION NAME"},
+	ColumnAddress:   {"STREET ADDRESS", "PHYSICAL ADDRESS", "PHYSICAL ADDRESS 1"},
+	ColumnTownName:  {"TOWN", "CITY", "CITY HEADING"},
+	ColumnCountry:   {"COUNTRY"},
+	ColumnTimeZone:  {"TIMEZONE", "TZ"},
+
+	ColumnBranchCode:       {"BRANCH"},
+	ColumnBranchInfo:       {"BRANCH INFO", "BRANCH NAME"},
+	ColumnZipCode:          {"ZIP", "POSTAL CODE", "POST CODE"},
+	ColumnModificationFlag: {"MODIFICATION", "FLAG"},
+	ColumnEffectiveDate:    {"VALID FROM", "EFFECTIVE FROM"},
+	ColumnModificationDate: {"LAST UPDATE DATE", "LAST MODIFICATION DATE"},
+}
+
+// ParseAliases reads extra header aliases written as
+// "COLUMN=alias,alias;COLUMN=alias", e.g. "SWIFT CODE=CODE;NAME=BANK",
+// which is how the command line and environment pass them.
+func ParseAliases(spec string) (map[Column][]string, error) {
+	aliases := make(map[Column][]string)
+	for _, entry := range strings.Split(spec, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, values, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("alias %q: want COLUMN=alias", entry)
+		}
+		col := Column(normalizeHeader(name))
+		if _, known := DefaultAliases[col]; !known {
+			return nil, fmt.Errorf("alias %q: unknown column %q", entry, col)
+		}
+		for _, value := range strings.Split(values, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				aliases[col] = append(aliases[col], value)
+			}
+		}
+	}
+	return aliases, nil
+}
+
+type MissingColumnsError struct {
+	Columns []Column
+}
+
+func (e *MissingColumnsError) Error() string {
+	names := make([]string, len(e.Columns))
+	for i, c := range e.Columns {
+		names[i] = string(c)
+	}
+	return fmt.Sprintf("missing required columns: %s", strings.Join(names, ", "))
+}
+
+type ColumnMap map[Column]int
+
+func normalizeHeader(h string) string {
+	h = strings.TrimPrefix(h, "\ufeff")
+	return strings.Join(strings.Fields(strings.ToUpper(h)), " ")
+}
+
+func MapColumns(header []string, aliases map[Column][]string) (ColumnMap, error) {
+	return mapColumns(header, RequiredColumns, OptionalColumns, aliases)
+}
+
+func mapColumns(header []string, required, optional []Column, aliases map[Column][]string) (ColumnMap, error) {
+	positions := make(map[string]int, len(header))
+	for i, h := range header {
+		name := normalizeHeader(h)
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+
+	columns := make(ColumnMap)
+	for _, col := range append(append([]Column{}, required...), optional...) {
+		candidates := append([]string{string(col)}, aliases[col]...)
+		candidates = append(candidates, DefaultAliases[col]...)
+		for _, candidate := range candidates {
+			if i, ok := positions[normalizeHeader(candidate)]; ok {
+				columns[col] = i
+				break
+			}
+		}
+	}
+
+	var missing []Column
+	for _, col := range required {
+		if _, ok := columns[col]; !ok {
+			missing = append(missing, col)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingColumnsError{Columns: missing}
+	}
+
+	return columns, nil
+}
+
+func (m ColumnMap) Get(record []string, col Column) string {
+	i, ok := m[col]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (m ColumnMap) Record(record []string) SwiftRecord {
+	swiftCode := m.Get(record, ColumnSwiftCode)
+	return SwiftRecord{
+		ISO2Code:      m.Get(record, ColumnISO2Code),
+		SwiftCode:     swiftCode,
+		CodeType:      m.Get(record, ColumnCodeType),
+		BankName:      m.Get(record, ColumnBankName),
+		Address:       m.Get(record, ColumnAddress),
+		TownName:      m.Get(record, ColumnTownName),
+		Country:       m.Get(record, ColumnCountry),
+		TimeZone:      m.Get(record, ColumnTimeZone),
+		IsHeadquarter: strings.HasSuffix(swiftCode, "XXX"),
+	}
+}
@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+)
+
+type CSVDecoder struct {
+	Aliases map[Column][]string
+	Comma   rune
+}
+
+func (d CSVDecoder) Decode(r io.Reader, fn func(SwiftRecord) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if d.Comma != 0 {
+		reader.Comma = d.Comma
+	}
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return errors.New("csv file has no header row")
+	}
+	if err != nil {
+		return err
+	}
+
+	columns, err := MapColumns(header, d.Aliases)
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+		record := columns.Record(row)
+		record.Row = line
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
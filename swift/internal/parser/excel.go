@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"errors"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+type ExcelDecoder struct {
+	Options
+}
+
+func (d ExcelDecoder) Decode(r io.Reader, fn func(SwiftRecord) error) error {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sheet := d.Sheet
+	if sheet == "" {
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return errors.New("workbook has no sheets")
+		}
+		sheet = sheets[0]
+	}
+
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Error(); err != nil {
+			return err
+		}
+		return errors.New("sheet " + sheet + " has no header row")
+	}
+	header, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	columns, err := MapColumns(header, d.Aliases)
+	if err != nil {
+		return err
+	}
+
+	line := 1
+	for rows.Next() {
+		line++
+		row, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		if len(row) == 0 {
+			continue
+		}
+		record := columns.Record(row)
+		record.Row = line
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	return rows.Error()
+}
+
+func ParseFromExcel(path string) ([]SwiftRecord, error) {
+	return ParseFromExcelWithOptions(path, Options{})
+}
+
+func ParseFromExcelWithOptions(path string, opts Options) ([]SwiftRecord, error) {
+	return ParseFile(path, FormatExcel, opts)
+}
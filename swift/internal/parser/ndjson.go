@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type ndjsonRecord struct {
+	SwiftCode   string `json:"swiftCode"`
+	CountryISO2 string `json:"countryISO2"`
+	CodeType    string `json:"codeType"`
+	BankName    string `json:"bankName"`
+	Address     string `json:"address"`
+	TownName    string `json:"townName"`
+	CountryName string `json:"countryName"`
+	TimeZone    string `json:"timeZone"`
+}
+
+type NDJSONDecoder struct{}
+
+func (d NDJSONDecoder) Decode(r io.Reader, fn func(SwiftRecord) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var raw ndjsonRecord
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		swiftCode := strings.TrimSpace(raw.SwiftCode)
+		err := fn(SwiftRecord{
+			Row:           line,
+			SwiftCode:     swiftCode,
+			ISO2Code:      strings.TrimSpace(raw.CountryISO2),
+			CodeType:      strings.TrimSpace(raw.CodeType),
+			BankName:      strings.TrimSpace(raw.BankName),
+			Address:       strings.TrimSpace(raw.Address),
+			TownName:      strings.TrimSpace(raw.TownName),
+			Country:       strings.TrimSpace(raw.CountryName),
+			TimeZone:      strings.TrimSpace(raw.TimeZone),
+			IsHeadquarter: strings.HasSuffix(swiftCode, "XXX"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -1,46 +1,130 @@
 package parser
 
 import (
-	"github.com/xuri/excelize/v2"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 type SwiftRecord struct {
+	Row           int
 	SwiftCode     string
 	ISO2Code      string
+	CodeType      string
 	BankName      string
 	Address       string
+	TownName      string
 	Country       string
+	TimeZone      string
 	IsHeadquarter bool
+
+	// The BIC directory fields below are read but not stored yet.
+	// ModificationFlag is its change marker; rows flagged D are skipped by
+	// the Validator.
+	BranchInfo       string
+	ZipCode          string
+	ModificationFlag string
+	EffectiveDate    time.Time
+	ModificationDate time.Time
+
+	// invalid is set by a decoder for a field it could not read, so that the
+	// Validator rejects the row instead of the whole file failing.
+	invalid string
+}
+
+type Options struct {
+	Sheet   string
+	Aliases map[Column][]string
+}
+
+type Format string
+
+const (
+	FormatExcel  Format = "xlsx"
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+
+	FormatBICDirectory Format = "bicdir"
+)
+
+type Decoder interface {
+	Decode(r io.Reader, fn func(SwiftRecord) error) error
 }
 
-func ParseFromExcel(path string) ([]SwiftRecord, error) {
-	f, err := excelize.OpenFile(path)
+func DecodeAll(d Decoder, r io.Reader) ([]SwiftRecord, error) {
+	var result []SwiftRecord
+	err := d.Decode(r, func(record SwiftRecord) error {
+		result = append(result, record)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
-	rows, err := f.GetRows("Sheet1")
-	if err != nil {
-		return nil, err
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx", ".xlsm":
+		return FormatExcel, nil
+	case ".csv":
+		return FormatCSV, nil
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	case ".txt", ".tsv":
+		return FormatBICDirectory, nil
+	}
+	return "", fmt.Errorf("cannot detect import format from file name %q", path)
+}
+
+func NewDecoder(format Format, opts Options) (Decoder, error) {
+	switch Format(strings.ToLower(string(format))) {
+	case FormatExcel, "xlsm", "excel":
+		return ExcelDecoder{Options: opts}, nil
+	case FormatCSV:
+		return CSVDecoder{Aliases: opts.Aliases}, nil
+	case FormatNDJSON, "jsonl":
+		return NDJSONDecoder{}, nil
+	case FormatBICDirectory, "swiftref":
+		return BICDirectoryDecoder{Aliases: opts.Aliases}, nil
 	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
 
-	var result []SwiftRecord
-	for i, record := range rows {
-		if i == 0 {
-			continue
+func StreamFile(path string, format Format, opts Options, fn func(SwiftRecord) error) error {
+	if format == "" {
+		detected, err := FormatFromPath(path)
+		if err != nil {
+			return err
 		}
-		isHeadquarter := strings.HasSuffix(record[1], "XXX")
-
-		result = append(result, SwiftRecord{
-			ISO2Code:      record[0],
-			SwiftCode:     record[1],
-			BankName:      record[3],
-			Address:       record[4],
-			Country:       record[6],
-			IsHeadquarter: isHeadquarter,
-		})
+		format = detected
+	}
+
+	decoder, err := NewDecoder(format, opts)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+
+	return decoder.Decode(f, fn)
+}
 
+func ParseFile(path string, format Format, opts Options) ([]SwiftRecord, error) {
+	var result []SwiftRecord
+	err := StreamFile(path, format, opts, func(record SwiftRecord) error {
+		result = append(result, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return result, nil
 }
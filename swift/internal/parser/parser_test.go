@@ -2,7 +2,12 @@ package parser
 
 import (
 	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseFromExcel(t *testing.T) {
@@ -15,6 +20,9 @@ func TestParseFromExcel(t *testing.T) {
 	require.Equal(t, "UNITED BANK OF ALBANIA SH.A", records[0].BankName)
 	require.Equal(t, "HYRJA 3 RR. DRITAN HOXHA ND. 11 TIRANA, TIRANA, 1023", records[0].Address)
 	require.Equal(t, "ALBANIA", records[0].Country)
+	require.Equal(t, "BIC11", records[0].CodeType)
+	require.Equal(t, "TIRANA", records[0].TownName)
+	require.Equal(t, "Europe/Tirane", records[0].TimeZone)
 
 	require.Equal(t, "AL", records[1060].ISO2Code)
 	require.Equal(t, "PYALALT2XXX", records[1060].SwiftCode)
@@ -22,3 +30,334 @@ func TestParseFromExcel(t *testing.T) {
 	require.Equal(t, "PALLATI DONIKA, FLOOR KATI 3 RR. FADIL RADA TIRANA, TIRANA, 1001", records[1060].Address)
 	require.Equal(t, "ALBANIA", records[1060].Country)
 }
+
+func writeWorkbook(t *testing.T, sheet string, rows [][]interface{}) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	if sheet != "Sheet1" {
+		_, err := f.NewSheet(sheet)
+		require.NoError(t, err)
+		require.NoError(t, f.DeleteSheet("Sheet1"))
+	}
+	for i, row := range rows {
+		cellName, err := excelize.CoordinatesToCellName(1, i+1)
+		require.NoError(t, err)
+		require.NoError(t, f.SetSheetRow(sheet, cellName, &row))
+	}
+	path := filepath.Join(t.TempDir(), "codes.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestParseFromExcelWithOptions(t *testing.T) {
+	t.Run("reordered and aliased headers on first sheet", func(t *testing.T) {
+		path := writeWorkbook(t, "Export", [][]interface{}{
+			{"Bank Name", "Country", "BIC", "City", "ISO2"},
+			{"PKO", "POLAND", "PKOPPLPWXXX", "WARSZAWA", "PL"},
+		})
+
+		records, err := ParseFromExcelWithOptions(path, Options{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "PKOPPLPWXXX", records[0].SwiftCode)
+		require.Equal(t, "PL", records[0].ISO2Code)
+		require.Equal(t, "PKO", records[0].BankName)
+		require.Equal(t, "POLAND", records[0].Country)
+		require.Equal(t, "WARSZAWA", records[0].TownName)
+		require.True(t, records[0].IsHeadquarter)
+	})
+
+	t.Run("custom aliases", func(t *testing.T) {
+		path := writeWorkbook(t, "Sheet1", [][]interface{}{
+			{"CTRY", "IDENTIFIER", "INSTITUTION", "LAND"},
+			{"PL", "PKOPPLPW002", "PKO", "POLAND"},
+		})
+
+		records, err := ParseFromExcelWithOptions(path, Options{Aliases: map[Column][]string{
+			ColumnISO2Code:  {"ctry"},
+			ColumnSwiftCode: {"identifier"},
+			ColumnBankName:  {"institution"},
+			ColumnCountry:   {"land"},
+		}})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "PKOPPLPW002", records[0].SwiftCode)
+		require.False(t, records[0].IsHeadquarter)
+	})
+
+	t.Run("missing required columns", func(t *testing.T) {
+		path := writeWorkbook(t, "Sheet1", [][]interface{}{
+			{"SWIFT CODE", "ADDRESS"},
+			{"PKOPPLPWXXX", "Warsaw"},
+		})
+
+		_, err := ParseFromExcelWithOptions(path, Options{})
+		var missing *MissingColumnsError
+		require.ErrorAs(t, err, &missing)
+		require.Equal(t, []Column{ColumnISO2Code, ColumnBankName, ColumnCountry}, missing.Columns)
+		require.ErrorContains(t, err, "COUNTRY ISO2 CODE, NAME, COUNTRY NAME")
+	})
+
+	t.Run("unknown sheet", func(t *testing.T) {
+		_, err := ParseFromExcelWithOptions("../../swift_codes.xlsx", Options{Sheet: "Missing"})
+		require.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("spreadsheet rows are accepted", func(t *testing.T) {
+		records, err := ParseFromExcel("../../swift_codes.xlsx")
+		require.NoError(t, err)
+
+		report := Validate(records)
+		require.Len(t, report.Accepted, 1061)
+		require.Empty(t, report.Rejected)
+		require.Equal(t, 2, report.Accepted[0].Row)
+	})
+
+	t.Run("rejects invalid rows with reasons", func(t *testing.T) {
+		records := []SwiftRecord{
+			{Row: 2, SwiftCode: "PKOPPLPWXXX", ISO2Code: "PL", BankName: "PKO", Country: "POLAND"},
+			{Row: 3, SwiftCode: "PKOP", ISO2Code: "PL", BankName: "PKO", Country: "POLAND"},
+			{Row: 4, SwiftCode: "PKOPDEPWXXX", ISO2Code: "PL", BankName: "PKO", Country: "POLAND"},
+			{Row: 5, SwiftCode: "PKOPPLPW002", ISO2Code: "PL", BankName: "", Country: "POLAND"},
+			{Row: 6, SwiftCode: "PKOPQQPWXXX", ISO2Code: "QQ", BankName: "PKO", Country: "NOWHERE"},
+			{Row: 8, SwiftCode: "1KOPPLPWXXX", ISO2Code: "PL", BankName: "PKO", Country: "POLAND"},
+			{Row: 9, SwiftCode: "PKOPPLPW003", ISO2Code: "PL", BankName: "PKO", Country: "POLAND", CodeType: "BRANCH"},
+		}
+
+		report := Validate(records)
+		require.Len(t, report.Accepted, 1)
+		require.Len(t, report.Rejected, 6)
+		require.Equal(t, 6, report.RejectedRows)
+
+		rows := make(map[int]string)
+		for _, r := range report.Rejected {
+			rows[r.Row] = r.Reason
+		}
+		require.Contains(t, rows[3], "8 or 11 characters")
+		require.Contains(t, rows[4], "does not match")
+		require.Contains(t, rows[5], "bank name")
+		require.Contains(t, rows[6], "unknown country")
+		require.Contains(t, rows[8], "institution")
+		require.Contains(t, rows[9], "code type must be at most 5 characters")
+	})
+
+	t.Run("lists rejects up to the cap and counts the rest", func(t *testing.T) {
+		v := NewValidator()
+		for row := 2; row < MaxReportIssues+12; row++ {
+			_, ok := v.Check(SwiftRecord{Row: row, SwiftCode: "pkop", ISO2Code: "PL", BankName: "PKO"})
+			require.False(t, ok)
+		}
+
+		report := v.Report()
+		require.Len(t, report.Rejected, MaxReportIssues)
+		require.Equal(t, MaxReportIssues+10, report.RejectedRows)
+		require.Len(t, report.Warnings, MaxReportIssues)
+		require.Equal(t, MaxReportIssues+10, report.WarningCount)
+	})
+
+	t.Run("leaves repeated codes to the store", func(t *testing.T) {
+		record := SwiftRecord{SwiftCode: "PKOPPLPWXXX", ISO2Code: "PL", BankName: "PKO", Country: "POLAND"}
+		report := Validate([]SwiftRecord{record, record})
+		require.Len(t, report.Accepted, 2)
+		require.Empty(t, report.Rejected)
+	})
+
+	t.Run("skips rows flagged as deleted", func(t *testing.T) {
+		records := []SwiftRecord{
+			{Row: 2, SwiftCode: "PKOPPLPWXXX", ISO2Code: "PL", BankName: "PKO", Country: "POLAND", ModificationFlag: "D"},
+			{Row: 3, SwiftCode: "PKOPPLPWXXX", ISO2Code: "PL", BankName: "PKO", Country: "POLAND", ModificationFlag: "M"},
+		}
+
+		report := Validate(records)
+		require.Len(t, report.Accepted, 1)
+		require.Equal(t, 3, report.Accepted[0].Row)
+		require.Empty(t, report.Rejected)
+		require.Equal(t, 1, report.Skipped)
+	})
+
+	t.Run("normalizes and warns", func(t *testing.T) {
+		records := []SwiftRecord{
+			{Row: 2, SwiftCode: "pkopplpw", ISO2Code: "pl", BankName: "PKO", Country: "POLSKA"},
+		}
+
+		report := Validate(records)
+		require.Len(t, report.Accepted, 1)
+		require.Empty(t, report.Rejected)
+		require.Len(t, report.Warnings, 3)
+		require.Equal(t, "PKOPPLPWXXX", report.Accepted[0].SwiftCode)
+		require.Equal(t, "PL", report.Accepted[0].ISO2Code)
+		require.True(t, report.Accepted[0].IsHeadquarter)
+	})
+}
+
+func TestCSVDecoder(t *testing.T) {
+	t.Run("maps columns by header", func(t *testing.T) {
+		input := "SWIFT CODE,NAME,COUNTRY ISO2 CODE,COUNTRY NAME,TOWN NAME,TIME ZONE\n" +
+			"PKOPPLPWXXX,PKO,PL,POLAND,WARSZAWA,Europe/Warsaw\n" +
+			"PKOPPLPW002,\"PKO, Krakow\",PL,POLAND,KRAKOW,Europe/Warsaw\n"
+
+		records, err := DecodeAll(CSVDecoder{}, strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, "PKOPPLPWXXX", records[0].SwiftCode)
+		require.True(t, records[0].IsHeadquarter)
+		require.Equal(t, 2, records[0].Row)
+		require.Equal(t, "PKO, Krakow", records[1].BankName)
+		require.Equal(t, "KRAKOW", records[1].TownName)
+		require.Equal(t, 3, records[1].Row)
+	})
+
+	t.Run("custom delimiter", func(t *testing.T) {
+		input := "BIC;BANK NAME;ISO2;COUNTRY\nPKOPPLPWXXX;PKO;PL;POLAND\n"
+
+		records, err := DecodeAll(CSVDecoder{Comma: ';'}, strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "POLAND", records[0].Country)
+	})
+
+	t.Run("missing columns", func(t *testing.T) {
+		_, err := DecodeAll(CSVDecoder{}, strings.NewReader("SWIFT CODE\nPKOPPLPWXXX\n"))
+		var missing *MissingColumnsError
+		require.ErrorAs(t, err, &missing)
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		_, err := DecodeAll(CSVDecoder{}, strings.NewReader(""))
+		require.ErrorContains(t, err, "no header row")
+	})
+}
+
+func TestNDJSONDecoder(t *testing.T) {
+	t.Run("decodes one record per line", func(t *testing.T) {
+		input := `{"swiftCode":"PKOPPLPWXXX","countryISO2":"PL","bankName":"PKO","countryName":"POLAND","townName":"WARSZAWA"}
+
+{"swiftCode":"PKOPPLPW002","countryISO2":"PL","bankName":"PKO","countryName":"POLAND","timeZone":"Europe/Warsaw"}
+`
+		records, err := DecodeAll(NDJSONDecoder{}, strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, "WARSZAWA", records[0].TownName)
+		require.True(t, records[0].IsHeadquarter)
+		require.Equal(t, 3, records[1].Row)
+		require.Equal(t, "Europe/Warsaw", records[1].TimeZone)
+		require.False(t, records[1].IsHeadquarter)
+	})
+
+	t.Run("malformed line", func(t *testing.T) {
+		_, err := DecodeAll(NDJSONDecoder{}, strings.NewReader("{\"swiftCode\":\"PKOPPLPWXXX\"}\n{oops\n"))
+		require.ErrorContains(t, err, "line 2")
+	})
+}
+
+func TestParseFile(t *testing.T) {
+	t.Run("format detected from extension", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "codes.csv")
+		require.NoError(t, os.WriteFile(path, []byte("BIC,NAME,ISO2,COUNTRY\nPKOPPLPWXXX,PKO,PL,POLAND\n"), 0o600))
+
+		records, err := ParseFile(path, "", Options{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+	})
+
+	t.Run("explicit format overrides extension", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "codes.txt")
+		require.NoError(t, os.WriteFile(path, []byte(`{"swiftCode":"PKOPPLPWXXX","countryISO2":"PL"}`+"\n"), 0o600))
+
+		_, err := ParseFile(path, "", Options{})
+		require.Error(t, err)
+
+		records, err := ParseFile(path, FormatNDJSON, Options{})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := ParseFile("../../swift_codes.xlsx", "xml", Options{})
+		require.ErrorContains(t, err, "unsupported import format")
+	})
+}
+
+func TestParseAliases(t *testing.T) {
+	aliases, err := ParseAliases("swift code=CODE, KOD ;NAME=BANK;")
+	require.NoError(t, err)
+	require.Equal(t, map[Column][]string{ColumnSwiftCode: {"CODE", "KOD"}, ColumnBankName: {"BANK"}}, aliases)
+
+	records, err := DecodeAll(CSVDecoder{Aliases: aliases}, strings.NewReader("KOD,BANK,ISO2,COUNTRY\nPKOPPLPWXXX,PKO,PL,POLAND\n"))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, "PKO", records[0].BankName)
+
+	_, err = ParseAliases("SWIFT CODE")
+	require.ErrorContains(t, err, "want COLUMN=alias")
+	_, err = ParseAliases("IBAN=NUMBER")
+	require.ErrorContains(t, err, `unknown column "IBAN"`)
+}
+
+func TestBICDirectoryDecoder(t *testing.T) {
+	t.Run("reads split BIC and extra fields", func(t *testing.T) {
+		input := "MODIFICATION FLAG\tBIC CODE\tBRANCH CODE\tINSTITUTION NAME\tBRANCH INFORMATION\tCITY HEADING\tZIP CODE\tCOUNTRY CODE\tEFFECTIVE DATE\tMODIFICATION DATE\n" +
+			"A\tPKOPPLPW\t\tPKO BANK POLSKI\t\tWARSZAWA\t02-515\tPL\t20240101\t2024-01-05\n" +
+			"M\tPKOPPLPW\t002\tPKO BANK POLSKI\tODDZIAL KRAKOW\tKRAKOW\t31-000\tPL\t\t\n"
+
+		records, err := DecodeAll(BICDirectoryDecoder{}, strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		hq := records[0]
+		require.Equal(t, "PKOPPLPWXXX", hq.SwiftCode)
+		require.True(t, hq.IsHeadquarter)
+		require.Equal(t, "BIC11", hq.CodeType)
+		require.Equal(t, "PL", hq.ISO2Code)
+		require.Equal(t, "POLAND", hq.Country)
+		require.Equal(t, "WARSZAWA", hq.TownName)
+		require.Equal(t, "02-515", hq.ZipCode)
+		require.Equal(t, "A", hq.ModificationFlag)
+		require.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), hq.EffectiveDate)
+		require.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), hq.ModificationDate)
+		require.Equal(t, 2, hq.Row)
+
+		branch := records[1]
+		require.Equal(t, "PKOPPLPW002", branch.SwiftCode)
+		require.False(t, branch.IsHeadquarter)
+		require.Equal(t, "ODDZIAL KRAKOW", branch.BranchInfo)
+		require.Equal(t, "M", branch.ModificationFlag)
+		require.True(t, branch.EffectiveDate.IsZero())
+	})
+
+	t.Run("full BIC column", func(t *testing.T) {
+		input := "BIC\tINSTITUTION NAME\tCOUNTRY CODE\tCOUNTRY NAME\nPKOPPLPW002\tPKO\tPL\tPOLSKA\n"
+
+		records, err := DecodeAll(BICDirectoryDecoder{}, strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		require.Equal(t, "PKOPPLPW002", records[0].SwiftCode)
+		require.Equal(t, "POLSKA", records[0].Country)
+	})
+
+	t.Run("invalid date rejects only its row", func(t *testing.T) {
+		input := "BIC\tINSTITUTION NAME\tCOUNTRY CODE\tEFFECTIVE DATE\n" +
+			"PKOPPLPWXXX\tPKO\tPL\tsoon\n" +
+			"BREXPLPWXXX\tMBANK\tPL\t20240101\n"
+
+		records, err := DecodeAll(BICDirectoryDecoder{}, strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		report := Validate(records)
+		require.Len(t, report.Accepted, 1)
+		require.Equal(t, "BREXPLPWXXX", report.Accepted[0].SwiftCode)
+		require.Len(t, report.Rejected, 1)
+		require.Equal(t, 2, report.Rejected[0].Row)
+		require.Contains(t, report.Rejected[0].Reason, "effective date")
+	})
+
+	t.Run("detected from extension", func(t *testing.T) {
+		format, err := FormatFromPath("bicdb.txt")
+		require.NoError(t, err)
+		require.Equal(t, FormatBICDirectory, format)
+	})
+}
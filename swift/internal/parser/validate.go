@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mbartnicki80/swift/internal/country"
+	"github.com/mbartnicki80/swift/internal/validation"
+)
+
+type RowIssue struct {
+	Row       int    `json:"row"`
+	SwiftCode string `json:"swiftCode"`
+	Reason    string `json:"reason"`
+}
+
+// MaxReportIssues caps how many rejects and how many warnings a Report
+// lists; past the cap they are only counted.
+const MaxReportIssues = 1000
+
+// Report summarizes a validation run. Skipped counts rows the file flags as
+// deleted (modification flag D); they are neither imported nor rejected.
+// Rejected and Warnings hold at most MaxReportIssues entries each, while
+// RejectedRows and WarningCount count all of them.
+type Report struct {
+	AcceptedRows int           `json:"accepted"`
+	RejectedRows int           `json:"rejectedRows"`
+	WarningCount int           `json:"warningCount"`
+	Skipped      int           `json:"skipped"`
+	Accepted     []SwiftRecord `json:"-"`
+	Rejected     []RowIssue    `json:"rejected"`
+	Warnings     []RowIssue    `json:"warnings"`
+}
+
+func addIssue(issues []RowIssue, issue RowIssue) []RowIssue {
+	if len(issues) >= MaxReportIssues {
+		return issues
+	}
+	return append(issues, issue)
+}
+
+func validateRecord(record *SwiftRecord) (reject string, warnings []string) {
+	if record.invalid != "" {
+		return record.invalid, nil
+	}
+	code := strings.ToUpper(record.SwiftCode)
+	if code != record.SwiftCode {
+		warnings = append(warnings, "SWIFT code converted to uppercase")
+	}
+	if err := validation.CheckBIC(code); err != nil {
+		return "SWIFT code " + err.Error(), warnings
+	}
+	if len(code) == 8 {
+		code += validation.HeadquarterBranch
+		warnings = append(warnings, "8-character SWIFT code expanded with XXX branch code")
+	}
+	record.SwiftCode = code
+	record.IsHeadquarter = validation.IsHeadquarterCode(code)
+
+	record.ISO2Code = strings.ToUpper(record.ISO2Code)
+	if record.ISO2Code != code[4:6] {
+		return fmt.Sprintf("country ISO2 code %q does not match SWIFT code characters 5-6 %q", record.ISO2Code, code[4:6]), warnings
+	}
+	name, ok := country.Name(record.ISO2Code)
+	if !ok {
+		return fmt.Sprintf("unknown country ISO2 code %q", record.ISO2Code), warnings
+	}
+	if record.BankName == "" {
+		return "bank name is empty", warnings
+	}
+	if err := validation.CheckCodeType(record.CodeType); err != nil {
+		return "code type " + err.Error(), warnings
+	}
+
+	if record.Country == "" {
+		record.Country = name
+		warnings = append(warnings, "country name is empty, filled in from ISO2 code")
+	} else if !strings.EqualFold(record.Country, name) {
+		warnings = append(warnings, fmt.Sprintf("country name %q differs from ISO 3166 name %q", record.Country, name))
+	}
+
+	return "", warnings
+}
+
+// Validator checks records one at a time and keeps only its capped report,
+// so its memory use does not depend on the size of the input. It does not
+// look for repeated codes: every store keeps the first row for a code and
+// skips the rest (ON CONFLICT DO NOTHING), which would need a set of every
+// code seen to report here.
+type Validator struct {
+	report Report
+}
+
+func NewValidator() *Validator {
+	return &Validator{
+		report: Report{
+			Rejected: []RowIssue{},
+			Warnings: []RowIssue{},
+		},
+	}
+}
+
+func (v *Validator) Check(record SwiftRecord) (SwiftRecord, bool) {
+	if record.ModificationFlag == "D" {
+		v.report.Skipped++
+		return record, false
+	}
+	original := record.SwiftCode
+	reject, warnings := validateRecord(&record)
+	for _, w := range warnings {
+		v.report.Warnings = addIssue(v.report.Warnings, RowIssue{Row: record.Row, SwiftCode: original, Reason: w})
+		v.report.WarningCount++
+	}
+	if reject != "" {
+		v.report.Rejected = addIssue(v.report.Rejected, RowIssue{Row: record.Row, SwiftCode: original, Reason: reject})
+		v.report.RejectedRows++
+		return record, false
+	}
+	v.report.AcceptedRows++
+	return record, true
+}
+
+func (v *Validator) Report() Report {
+	return v.report
+}
+
+func Validate(records []SwiftRecord) Report {
+	v := NewValidator()
+	var accepted []SwiftRecord
+	for _, record := range records {
+		if record, ok := v.Check(record); ok {
+			accepted = append(accepted, record)
+		}
+	}
+
+	report := v.Report()
+	report.Accepted = accepted
+	return report
+}
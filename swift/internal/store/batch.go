@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mbartnicki80/swift/internal/parser"
+)
+
+const DefaultBatchSize = 1000
+
+type BatchInserter struct {
+	db       *sql.DB
+	size     int
+	batch    []parser.SwiftRecord
+	inserted int
+	importID *int64
+}
+
+func NewBatchInserter(db *sql.DB, size int) *BatchInserter {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	return &BatchInserter{
+		db:    db,
+		size:  size,
+		batch: make([]parser.SwiftRecord, 0, size),
+	}
+}
+
+func (b *BatchInserter) SetImportID(id int64) {
+	b.importID = &id
+}
+
+func (b *BatchInserter) Add(ctx context.Context, record parser.SwiftRecord) error {
+	b.batch = append(b.batch, record)
+	if len(b.batch) >= b.size {
+		return b.Flush(ctx)
+	}
+	return nil
+}
+
+func (b *BatchInserter) Flush(ctx context.Context) error {
+	if len(b.batch) == 0 {
+		return nil
+	}
+	if err := insertRows(ctx, b.db, b.batch, b.importID); err != nil {
+		return err
+	}
+	b.inserted += len(b.batch)
+	b.batch = b.batch[:0]
+	return nil
+}
+
+func (b *BatchInserter) Close(ctx context.Context) error {
+	if err := b.Flush(ctx); err != nil {
+		return err
+	}
+	return LinkOrphanBranches(ctx, b.db)
+}
+
+func (b *BatchInserter) Inserted() int {
+	return b.inserted
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func LinkOrphanBranches(ctx context.Context, db *sql.DB) error {
+	return linkOrphanBranches(ctx, db)
+}
+
+func linkOrphanBranches(ctx context.Context, ex execer) error {
+	linkQuery := `
+		UPDATE branches
+		SET headquarter = swift_codes.swift_code
+		FROM swift_codes
+		WHERE branches.headquarter IS NULL
+		  AND swift_codes.is_headquarter
+		  AND swift_codes.swift_code = LEFT(branches.swift_code, 8) || 'XXX'
+	`
+	_, err := ex.ExecContext(ctx, linkQuery)
+	return err
+}
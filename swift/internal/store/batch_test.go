@@ -0,0 +1,91 @@
+package store
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var linkOrphanBranchesQuery = `
+UPDATE branches
+SET headquarter = swift_codes.swift_code
+FROM swift_codes
+WHERE branches.headquarter IS NULL
+  AND swift_codes.is_headquarter
+  AND swift_codes.swift_code = LEFT(branches.swift_code, 8) || 'XXX'
+`
+
+func TestBatchInserter(t *testing.T) {
+	branch := parser.SwiftRecord{ISO2Code: "PL", SwiftCode: "PKOPPLPW002", BankName: "PKO", Address: "Krakow", Country: "Poland"}
+	hq := parser.SwiftRecord{ISO2Code: "PL", SwiftCode: "PKOPPLPWXXX", BankName: "PKO", Address: "Warsaw", Country: "Poland", IsHeadquarter: true}
+
+	expectInsert := func(mock sqlmock.Sqlmock, r parser.SwiftRecord) {
+		mock.ExpectExec(importSwiftCodeQuery).
+			WithArgs(r.ISO2Code, r.SwiftCode, r.BankName, r.Address, r.Country, r.IsHeadquarter,
+				r.CodeType, r.TownName, r.TimeZone, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+
+	t.Run("flushes full batches and links orphans on close", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectInsert(mock, branch)
+		mock.ExpectQuery(selectExists).
+			WithArgs("PKOPPLPWXXX").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(insertBranchQuery).
+			WithArgs(branch.SwiftCode, nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		mock.ExpectBegin()
+		expectInsert(mock, hq)
+		mock.ExpectCommit()
+
+		mock.ExpectExec(linkOrphanBranchesQuery).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		inserter := NewBatchInserter(db, 1)
+		require.NoError(t, inserter.Add(t.Context(), branch))
+		require.NoError(t, inserter.Add(t.Context(), hq))
+		require.NoError(t, inserter.Close(t.Context()))
+		require.Equal(t, 2, inserter.Inserted())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("partial batch is flushed on close", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectInsert(mock, hq)
+		mock.ExpectCommit()
+		mock.ExpectExec(linkOrphanBranchesQuery).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		inserter := NewBatchInserter(db, 10)
+		require.NoError(t, inserter.Add(t.Context(), hq))
+		require.Equal(t, 0, inserter.Inserted())
+		require.NoError(t, inserter.Close(t.Context()))
+		require.Equal(t, 1, inserter.Inserted())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("flush error is returned", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin().WillReturnError(errors.New("begin failed"))
+
+		inserter := NewBatchInserter(db, 1)
+		require.ErrorContains(t, inserter.Add(t.Context(), hq), "begin failed")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,270 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mbartnicki80/swift/internal/model"
+)
+
+// BulkResult holds one error per item (nil on success) and whether the
+// transaction was committed. In atomic mode a single failed item rolls back
+// every other item.
+type BulkResult struct {
+	Errors    []error
+	Committed bool
+}
+
+func (r BulkResult) Failed() int {
+	failed := 0
+	for _, err := range r.Errors {
+		if err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+func finishBulk(tx *sql.Tx, result BulkResult, atomic bool) (BulkResult, error) {
+	if atomic && result.Failed() > 0 {
+		return result, tx.Rollback()
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	result.Committed = true
+	return result, nil
+}
+
+const bulkInsertQuery = `
+	INSERT INTO swift_codes (country_iso2_code, swift_code,
+	                         bank_name, address,
+	                         country_name, is_headquarter,
+	                         code_type, town_name, time_zone)
+	SELECT * FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[],
+	                     $6::boolean[], $7::text[], $8::text[], $9::text[])
+	ON CONFLICT (swift_code) DO NOTHING
+	RETURNING swift_code
+`
+
+const bulkBranchQuery = `
+	INSERT INTO branches (swift_code, headquarter)
+	SELECT incoming.swift_code, hq.swift_code
+	FROM unnest($1::text[]) AS incoming(swift_code)
+	LEFT JOIN swift_codes hq ON hq.swift_code = LEFT(incoming.swift_code, 8) || 'XXX'
+	ON CONFLICT (swift_code) DO NOTHING
+`
+
+// insertCodes inserts codes with one multi-row statement and returns the
+// set of codes that were inserted; the rest already existed.
+func insertCodes(ctx context.Context, tx *sql.Tx, codes []model.SwiftCode) (map[string]bool, error) {
+	columns := make([][]string, 8)
+	headquarters := make([]bool, len(codes))
+	for i, code := range codes {
+		for c, value := range []string{code.CountryISO2, code.SwiftCode, code.BankName, code.Address,
+			code.CountryName, code.CodeType, code.TownName, code.TimeZone} {
+			columns[c] = append(columns[c], value)
+		}
+		headquarters[i] = code.IsHeadquarter
+	}
+
+	rows, err := tx.QueryContext(ctx, bulkInsertQuery,
+		pq.Array(columns[0]), pq.Array(columns[1]), pq.Array(columns[2]), pq.Array(columns[3]),
+		pq.Array(columns[4]), pq.Array(headquarters), pq.Array(columns[5]), pq.Array(columns[6]),
+		pq.Array(columns[7]))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inserted := make(map[string]bool, len(codes))
+	for rows.Next() {
+		var swiftCode string
+		if err := rows.Scan(&swiftCode); err != nil {
+			return nil, err
+		}
+		inserted[swiftCode] = true
+	}
+	return inserted, rows.Err()
+}
+
+// BulkCreate inserts codes in one transaction with a single multi-row
+// statement. Codes that already exist, or repeat an earlier item, fail with
+// ErrConflict. If the statement fails, the items are retried one at a time
+// under a savepoint so that only the offending items fail.
+func BulkCreate(ctx context.Context, db *sql.DB, codes []model.SwiftCode, atomic bool) (BulkResult, error) {
+	result := BulkResult{Errors: make([]error, len(codes))}
+
+	seen := make(map[string]bool, len(codes))
+	var pending []int
+	var batch []model.SwiftCode
+	for i, code := range codes {
+		if seen[code.SwiftCode] {
+			result.Errors[i] = ErrConflict
+			continue
+		}
+		seen[code.SwiftCode] = true
+		pending = append(pending, i)
+		batch = append(batch, code)
+	}
+	if atomic && result.Failed() > 0 {
+		return result, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+
+	var inserted map[string]bool
+	batchErr, err := withSavepoint(ctx, tx, func() error {
+		var err error
+		inserted, err = insertCodes(ctx, tx, batch)
+		return err
+	})
+	if err == nil && batchErr != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		tx.Rollback()
+		return result, ContextError(ctx, err)
+	}
+	if batchErr != nil {
+		inserted = make(map[string]bool, len(pending))
+		for _, i := range pending {
+			var created map[string]bool
+			itemErr, err := withSavepoint(ctx, tx, func() error {
+				var err error
+				created, err = insertCodes(ctx, tx, codes[i:i+1])
+				return err
+			})
+			if err == nil && itemErr != nil {
+				err = ctx.Err()
+			}
+			if err != nil {
+				tx.Rollback()
+				return result, ContextError(ctx, err)
+			}
+			if itemErr != nil {
+				result.Errors[i] = itemErr
+				continue
+			}
+			inserted[codes[i].SwiftCode] = created[codes[i].SwiftCode]
+		}
+	}
+
+	var branches []string
+	for _, i := range pending {
+		code := codes[i]
+		if result.Errors[i] != nil {
+			continue
+		}
+		if !inserted[code.SwiftCode] {
+			result.Errors[i] = ErrConflict
+			continue
+		}
+		if !code.IsHeadquarter {
+			branches = append(branches, code.SwiftCode)
+		}
+	}
+	if atomic && result.Failed() > 0 {
+		return result, tx.Rollback()
+	}
+
+	if len(branches) > 0 {
+		if _, err := tx.ExecContext(ctx, bulkBranchQuery, pq.Array(branches)); err != nil {
+			tx.Rollback()
+			return result, err
+		}
+	}
+	if err := linkOrphanBranches(ctx, tx); err != nil {
+		tx.Rollback()
+		return result, err
+	}
+	return finishBulk(tx, result, atomic)
+}
+
+// BranchesFirst returns the indexes of codes with every branch ahead of the
+// headquarters, otherwise in input order, so that a bulk delete can remove a
+// headquarter together with its branches.
+func BranchesFirst(codes []string) []int {
+	order := make([]int, len(codes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return !strings.HasSuffix(codes[order[a]], "XXX") && strings.HasSuffix(codes[order[b]], "XXX")
+	})
+	return order
+}
+
+// BulkDelete deletes codes in one transaction. Branches are deleted before
+// headquarters so a headquarter can be removed together with its branches;
+// each delete runs under a savepoint so a failed item does not abort the rest.
+func BulkDelete(ctx context.Context, db *sql.DB, codes []string, atomic bool) (BulkResult, error) {
+	result := BulkResult{Errors: make([]error, len(codes))}
+
+	order := BranchesFirst(codes)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+
+	for _, i := range order {
+		err := deleteWithSavepoint(ctx, tx, codes[i])
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrHasBranches) {
+			result.Errors[i] = err
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return result, err
+		}
+	}
+	return finishBulk(tx, result, atomic)
+}
+
+func deleteWithSavepoint(ctx context.Context, tx *sql.Tx, swiftCode string) error {
+	var deleted int64
+	itemErr, err := withSavepoint(ctx, tx, func() error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM swift_codes WHERE swift_code=$1", swiftCode)
+		if err != nil {
+			return err
+		}
+		deleted, err = res.RowsAffected()
+		return err
+	})
+	switch {
+	case err != nil:
+		return err
+	case isForeignKeyViolation(itemErr):
+		return ErrHasBranches
+	case itemErr != nil:
+		return itemErr
+	case deleted == 0:
+		return ErrNotFound
+	}
+	return nil
+}
+
+// withSavepoint runs fn under a savepoint. When fn fails, the savepoint is
+// rolled back so the transaction stays usable and fn's error is returned as
+// itemErr; err is set only when the savepoint statements themselves fail.
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func() error) (itemErr, err error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_item"); err != nil {
+		return nil, err
+	}
+	if itemErr := fn(); itemErr != nil {
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_item"); err != nil {
+			return itemErr, err
+		}
+		return itemErr, nil
+	}
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_item")
+	return nil, err
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bulkCodes() []model.SwiftCode {
+	return []model.SwiftCode{
+		{SwiftCode: "PKOPPLPWXXX", CountryISO2: "PL", BankName: "PKO", CountryName: "POLAND", IsHeadquarter: true},
+		{SwiftCode: "PKOPPLPW002", CountryISO2: "PL", BankName: "PKO", CountryName: "POLAND"},
+		{SwiftCode: "BREXPLPWXXX", CountryISO2: "PL", BankName: "MBANK", CountryName: "POLAND", IsHeadquarter: true},
+	}
+}
+
+func bulkInsertArgs(codes ...model.SwiftCode) []driver.Value {
+	columns := make([][]string, 8)
+	headquarters := make([]bool, len(codes))
+	for i, code := range codes {
+		for c, value := range []string{code.CountryISO2, code.SwiftCode, code.BankName, code.Address,
+			code.CountryName, code.CodeType, code.TownName, code.TimeZone} {
+			columns[c] = append(columns[c], value)
+		}
+		headquarters[i] = code.IsHeadquarter
+	}
+	return []driver.Value{
+		pq.Array(columns[0]), pq.Array(columns[1]), pq.Array(columns[2]), pq.Array(columns[3]),
+		pq.Array(columns[4]), pq.Array(headquarters), pq.Array(columns[5]), pq.Array(columns[6]),
+		pq.Array(columns[7]),
+	}
+}
+
+func TestBulkCreate(t *testing.T) {
+	t.Run("best effort commits the inserted codes", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		codes := append(bulkCodes(), bulkCodes()[0])
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(bulkInsertQuery).WithArgs(bulkInsertArgs(bulkCodes()...)...).
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code"}).AddRow("PKOPPLPWXXX").AddRow("PKOPPLPW002"))
+		mock.ExpectExec("RELEASE SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(bulkBranchQuery).WithArgs(pq.Array([]string{"PKOPPLPW002"})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(linkOrphanBranchesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		result, err := BulkCreate(t.Context(), db, codes, false)
+		require.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, []error{nil, nil, ErrConflict, ErrConflict}, result.Errors)
+		assert.Equal(t, 2, result.Failed())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("atomic rolls back on conflict", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(bulkInsertQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code"}).AddRow("PKOPPLPWXXX").AddRow("PKOPPLPW002"))
+		mock.ExpectExec("RELEASE SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		result, err := BulkCreate(t.Context(), db, bulkCodes(), true)
+		require.NoError(t, err)
+		assert.False(t, result.Committed)
+		assert.Equal(t, ErrConflict, result.Errors[2])
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failing item is retried alone and fails by itself", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		codes := bulkCodes()
+		codes[2].CodeType = "TOO LONG"
+		tooLong := &pq.Error{Code: "22001"}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(bulkInsertQuery).WillReturnError(tooLong)
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		for _, code := range codes[:2] {
+			mock.ExpectExec("SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(bulkInsertQuery).WithArgs(bulkInsertArgs(code)...).
+				WillReturnRows(sqlmock.NewRows([]string{"swift_code"}).AddRow(code.SwiftCode))
+			mock.ExpectExec("RELEASE SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		mock.ExpectExec("SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(bulkInsertQuery).WithArgs(bulkInsertArgs(codes[2])...).WillReturnError(tooLong)
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(bulkBranchQuery).WithArgs(pq.Array([]string{"PKOPPLPW002"})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(linkOrphanBranchesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		result, err := BulkCreate(t.Context(), db, codes, false)
+		require.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, []error{nil, nil, tooLong}, result.Errors)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestBulkDelete(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteSwiftCodeQuery).WithArgs("PKOPPLPW002").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteSwiftCodeQuery).WithArgs("NOPENOPE123").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteSwiftCodeQuery).WithArgs("PKOPPLPWXXX").WillReturnError(&pq.Error{Code: foreignKeyViolation})
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT bulk_item").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	result, err := BulkDelete(t.Context(), db, []string{"PKOPPLPWXXX", "PKOPPLPW002", "NOPENOPE123"}, false)
+	require.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.Equal(t, []error{ErrHasBranches, nil, ErrNotFound}, result.Errors)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBranchesFirst(t *testing.T) {
+	codes := []string{"PKOPPLPWXXX", "PKOPPLPW002", "BREXPLPWXXX", "PKOPPLPW001"}
+	assert.Equal(t, []int{1, 3, 0, 2}, BranchesFirst(codes))
+}
@@ -1,26 +1,25 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"github.com/mbartnicki80/swift/internal/model"
 	"github.com/mbartnicki80/swift/internal/parser"
 	"log"
 )
 
-func InsertBranches(tx *sql.Tx, records []parser.SwiftRecord) error {
+func insertBranchRows(ctx context.Context, tx *sql.Tx, records []parser.SwiftRecord) error {
 	insertIntoBranchQuery := `
 		INSERT INTO branches (swift_code, headquarter)
 		VALUES ($1, $2)
 		ON CONFLICT (swift_code) DO NOTHING
 	`
-	var err error
 	for _, record := range records {
 		if !record.IsHeadquarter {
 			hqCode := record.SwiftCode[:8] + "XXX"
 			var exists bool
-			err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM swift_codes WHERE swift_code = $1)", hqCode).Scan(&exists)
+			err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM swift_codes WHERE swift_code = $1)", hqCode).Scan(&exists)
 			if err != nil {
-				tx.Rollback()
 				return err
 			}
 
@@ -31,58 +30,88 @@ func InsertBranches(tx *sql.Tx, records []parser.SwiftRecord) error {
 				hqPtr = &hqCode
 			}
 
-			_, err = tx.Exec(insertIntoBranchQuery, record.SwiftCode, hqPtr)
+			_, err = tx.ExecContext(ctx, insertIntoBranchQuery, record.SwiftCode, hqPtr)
 			if err != nil {
-				tx.Rollback()
 				return err
 			}
 		}
 	}
-	return tx.Commit()
+	return nil
 }
 
-func InsertRowsToDatabase(db *sql.DB, records []parser.SwiftRecord) error {
-	tx, err := db.Begin()
-	if err != nil {
+func InsertBranches(ctx context.Context, tx *sql.Tx, records []parser.SwiftRecord) error {
+	if err := insertBranchRows(ctx, tx, records); err != nil {
+		tx.Rollback()
 		return err
 	}
+	return tx.Commit()
+}
+
+func InsertRowsToDatabase(ctx context.Context, db *sql.DB, records []parser.SwiftRecord) error {
+	return insertRows(ctx, db, records, nil)
+}
 
+// insertRecords reports, per record, whether it was inserted or skipped
+// because the code already exists.
+func insertRecords(ctx context.Context, tx *sql.Tx, records []parser.SwiftRecord, importID *int64) ([]bool, error) {
 	insertSwiftCodeQuery := `
 		INSERT INTO swift_codes (country_iso2_code, swift_code,
 		                         bank_name, address,
-		                         country_name, is_headquarter)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		                         country_name, is_headquarter,
+		                         code_type, town_name, time_zone,
+		                         import_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		ON CONFLICT (swift_code) DO NOTHING
 	`
 
-	for _, record := range records {
-		_, err := tx.Exec(insertSwiftCodeQuery, record.ISO2Code, record.SwiftCode, record.BankName,
-			record.Address, record.Country, record.IsHeadquarter)
-
+	inserted := make([]bool, len(records))
+	for i, record := range records {
+		res, err := tx.ExecContext(ctx, insertSwiftCodeQuery, record.ISO2Code, record.SwiftCode, record.BankName,
+			record.Address, record.Country, record.IsHeadquarter,
+			record.CodeType, record.TownName, record.TimeZone, importID)
 		if err != nil {
-			tx.Rollback()
-			return err
+			return nil, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
 		}
+		inserted[i] = affected > 0
 	}
+	return inserted, nil
+}
 
-	return InsertBranches(tx, records)
+func insertRows(ctx context.Context, db *sql.DB, records []parser.SwiftRecord, importID *int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := insertRecords(ctx, tx, records, importID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return InsertBranches(ctx, tx, records)
 }
 
-func FetchSwiftCode(db *sql.DB, swiftCode string) (model.SwiftCode, []model.SwiftCode, error) {
+func FetchSwiftCode(ctx context.Context, db *sql.DB, swiftCode string) (model.SwiftCode, []model.SwiftCode, error) {
 	FetchSwiftCodeQuery := `
-	SELECT swift_code, address, country_name, is_headquarter, country_iso2_code, bank_name
+	SELECT swift_code, address, country_name, is_headquarter, country_iso2_code, bank_name,
+	       code_type, town_name, time_zone
 	FROM swift_codes
 	WHERE swift_code = $1
 	`
 	FetchBranchQuery := `
 	SELECT swift_codes.swift_code, swift_codes.address, swift_codes.is_headquarter,
-		   swift_codes.country_iso2_code, swift_codes.bank_name
+		   swift_codes.country_iso2_code, swift_codes.bank_name,
+		   swift_codes.code_type, swift_codes.town_name, swift_codes.time_zone
 	FROM branches
 	JOIN swift_codes ON swift_codes.swift_code = branches.swift_code
 	WHERE branches.headquarter = $1
 	`
 
-	res, err := db.Query(FetchSwiftCodeQuery, swiftCode)
+	res, err := db.QueryContext(ctx, FetchSwiftCodeQuery, swiftCode)
 	if err != nil {
 		return model.SwiftCode{}, nil, err
 	}
@@ -94,11 +123,12 @@ func FetchSwiftCode(db *sql.DB, swiftCode string) (model.SwiftCode, []model.Swif
 	}(res)
 
 	if !res.Next() {
-		return model.SwiftCode{}, nil, sql.ErrNoRows
+		return model.SwiftCode{}, nil, ErrNotFound
 	}
 
 	var result model.SwiftCode
-	err = res.Scan(&result.SwiftCode, &result.Address, &result.CountryName, &result.IsHeadquarter, &result.CountryISO2, &result.BankName)
+	err = res.Scan(&result.SwiftCode, &result.Address, &result.CountryName, &result.IsHeadquarter, &result.CountryISO2, &result.BankName,
+		&result.CodeType, &result.TownName, &result.TimeZone)
 	if err != nil {
 		return model.SwiftCode{}, nil, err
 	}
@@ -107,7 +137,7 @@ func FetchSwiftCode(db *sql.DB, swiftCode string) (model.SwiftCode, []model.Swif
 		return result, nil, nil
 	}
 
-	rows, err := db.Query(FetchBranchQuery, swiftCode)
+	rows, err := db.QueryContext(ctx, FetchBranchQuery, swiftCode)
 	if err != nil {
 		return result, nil, err
 	}
@@ -121,7 +151,8 @@ func FetchSwiftCode(db *sql.DB, swiftCode string) (model.SwiftCode, []model.Swif
 	var branches []model.SwiftCode
 	for rows.Next() {
 		var branch model.SwiftCode
-		err := rows.Scan(&branch.SwiftCode, &branch.Address, &branch.IsHeadquarter, &branch.CountryISO2, &branch.BankName)
+		err := rows.Scan(&branch.SwiftCode, &branch.Address, &branch.IsHeadquarter, &branch.CountryISO2, &branch.BankName,
+			&branch.CodeType, &branch.TownName, &branch.TimeZone)
 		if err != nil {
 			return result, nil, err
 		}
@@ -135,13 +166,14 @@ func FetchSwiftCode(db *sql.DB, swiftCode string) (model.SwiftCode, []model.Swif
 	return result, branches, nil
 }
 
-func FetchSwiftCodesByCountry(db *sql.DB, countryCode string) ([]model.SwiftCode, error) {
+func FetchSwiftCodesByCountry(ctx context.Context, db *sql.DB, countryCode string) ([]model.SwiftCode, error) {
 	FetchSwiftCodesByCountryQuery := `
-		SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name
+		SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+		       code_type, town_name, time_zone
 		FROM swift_codes
 		WHERE country_iso2_code = $1
 		`
-	rows, err := db.Query(FetchSwiftCodesByCountryQuery, countryCode)
+	rows, err := db.QueryContext(ctx, FetchSwiftCodesByCountryQuery, countryCode)
 	if err != nil {
 		return nil, err
 	}
@@ -155,7 +187,8 @@ func FetchSwiftCodesByCountry(db *sql.DB, countryCode string) ([]model.SwiftCode
 	var results []model.SwiftCode
 	for rows.Next() {
 		var result model.SwiftCode
-		err := rows.Scan(&result.Address, &result.BankName, &result.CountryISO2, &result.IsHeadquarter, &result.SwiftCode, &result.CountryName)
+		err := rows.Scan(&result.Address, &result.BankName, &result.CountryISO2, &result.IsHeadquarter, &result.SwiftCode, &result.CountryName,
+			&result.CodeType, &result.TownName, &result.TimeZone)
 		if err != nil {
 			return nil, err
 		}
@@ -164,25 +197,34 @@ func FetchSwiftCodesByCountry(db *sql.DB, countryCode string) ([]model.SwiftCode
 	return results, nil
 }
 
-func InsertNewSwiftCode(db *sql.DB, swiftCode model.SwiftCode) error {
+func InsertNewSwiftCode(ctx context.Context, db *sql.DB, swiftCode model.SwiftCode) error {
 	insertSwiftCodeQuery := `
 		INSERT INTO swift_codes (country_iso2_code, swift_code,
 		                         bank_name, address, country_name, 
-		                         is_headquarter)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		                         is_headquarter, code_type,
+		                         town_name, time_zone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (swift_code) DO NOTHING
 	`
 
-	_, err := db.Exec(insertSwiftCodeQuery, swiftCode.CountryISO2, swiftCode.SwiftCode, swiftCode.BankName,
-		swiftCode.Address, swiftCode.CountryName, swiftCode.IsHeadquarter)
+	res, err := db.ExecContext(ctx, insertSwiftCodeQuery, swiftCode.CountryISO2, swiftCode.SwiftCode, swiftCode.BankName,
+		swiftCode.Address, swiftCode.CountryName, swiftCode.IsHeadquarter,
+		swiftCode.CodeType, swiftCode.TownName, swiftCode.TimeZone)
+	if err != nil {
+		return err
+	}
+	inserted, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if inserted == 0 {
+		return ErrConflict
+	}
 
-	if !swiftCode.IsHeadquarter {
+	if !swiftCode.IsHeadquarter && len(swiftCode.SwiftCode) == 11 {
 		hq := swiftCode.SwiftCode[:8] + "XXX"
 		var exists bool
-		err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM swift_codes WHERE swift_code = $1)", hq).Scan(&exists)
+		err = db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM swift_codes WHERE swift_code = $1)", hq).Scan(&exists)
 		if err != nil {
 			return err
 		}
@@ -198,7 +240,7 @@ func InsertNewSwiftCode(db *sql.DB, swiftCode model.SwiftCode) error {
 		VALUES ($1, $2)
 		ON CONFLICT (swift_code) DO NOTHING
 		`
-		_, err = db.Exec(insertIntoBranchQuery, swiftCode.SwiftCode, hqPtr)
+		_, err = db.ExecContext(ctx, insertIntoBranchQuery, swiftCode.SwiftCode, hqPtr)
 		if err != nil {
 			return err
 		}
@@ -208,7 +250,7 @@ func InsertNewSwiftCode(db *sql.DB, swiftCode model.SwiftCode) error {
 			SET headquarter = $1
 			WHERE headquarter IS NULL AND swift_code LIKE $2
 		`
-		_, err = db.Exec(updateBranchesQuery, swiftCode.SwiftCode, swiftCode.SwiftCode[:8]+"%")
+		_, err = db.ExecContext(ctx, updateBranchesQuery, swiftCode.SwiftCode, swiftCode.SwiftCode[:8]+"%")
 		if err != nil {
 			return err
 		}
@@ -217,11 +259,84 @@ func InsertNewSwiftCode(db *sql.DB, swiftCode model.SwiftCode) error {
 	return nil
 }
 
-func DeleteSwiftCode(db *sql.DB, swiftCode string) error {
+func DeleteSwiftCode(ctx context.Context, db *sql.DB, swiftCode string) error {
 	deleteQuery := "DELETE FROM swift_codes WHERE swift_code=$1"
-	_, err := db.Exec(deleteQuery, swiftCode)
+	res, err := db.ExecContext(ctx, deleteQuery, swiftCode)
+	if isForeignKeyViolation(err) {
+		return ErrHasBranches
+	}
+	if err != nil {
+		return err
+	}
+	deleted, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
+
+func fetchForUpdate(ctx context.Context, tx *sql.Tx, swiftCode string) (model.SwiftCode, error) {
+	fetchForUpdateQuery := `
+		SELECT swift_code, address, country_name, is_headquarter, country_iso2_code, bank_name,
+		       code_type, town_name, time_zone
+		FROM swift_codes
+		WHERE swift_code = $1
+		FOR UPDATE
+	`
+	var current model.SwiftCode
+	var address sql.NullString
+	err := tx.QueryRowContext(ctx, fetchForUpdateQuery, swiftCode).Scan(&current.SwiftCode, &address, &current.CountryName,
+		&current.IsHeadquarter, &current.CountryISO2, &current.BankName,
+		&current.CodeType, &current.TownName, &current.TimeZone)
+	current.Address = address.String
+	return current, err
+}
+
+func updateSwiftCode(ctx context.Context, db *sql.DB, swiftCode string, change func(model.SwiftCode) model.SwiftCode) (model.SwiftCode, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.SwiftCode{}, err
+	}
+
+	current, err := fetchForUpdate(ctx, tx, swiftCode)
+	if err != nil {
+		tx.Rollback()
+		return model.SwiftCode{}, notFound(err)
+	}
+	// isHeadquarter follows the XXX suffix (see validation.SwiftCode), so an
+	// update can move neither the code nor its place in the branches table.
+	updated := change(current)
+	updated.SwiftCode = current.SwiftCode
+	updated.IsHeadquarter = current.IsHeadquarter
+
+	updateSwiftCodeQuery := `
+		UPDATE swift_codes
+		SET country_iso2_code = $2, bank_name = $3, address = $4, country_name = $5,
+		    is_headquarter = $6, code_type = $7, town_name = $8, time_zone = $9,
+		    import_id = NULL
+		WHERE swift_code = $1
+	`
+	_, err = tx.ExecContext(ctx, updateSwiftCodeQuery, updated.SwiftCode, updated.CountryISO2, updated.BankName,
+		updated.Address, updated.CountryName, updated.IsHeadquarter,
+		updated.CodeType, updated.TownName, updated.TimeZone)
+	if err != nil {
+		tx.Rollback()
+		return model.SwiftCode{}, err
+	}
+
+	return updated, tx.Commit()
+}
+
+func UpdateSwiftCode(ctx context.Context, db *sql.DB, swiftCode model.SwiftCode) error {
+	_, err := updateSwiftCode(ctx, db, swiftCode.SwiftCode, func(model.SwiftCode) model.SwiftCode {
+		return swiftCode
+	})
+	return err
+}
+
+func PatchSwiftCode(ctx context.Context, db *sql.DB, swiftCode string, patch model.SwiftCodePatch) (model.SwiftCode, error) {
+	return updateSwiftCode(ctx, db, swiftCode, patch.Apply)
+}
@@ -1,9 +1,9 @@
 package store
 
 import (
-	"database/sql"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/mbartnicki80/swift/internal/model"
 	"github.com/mbartnicki80/swift/internal/parser"
 	"github.com/stretchr/testify/require"
@@ -14,8 +14,19 @@ var (
 	insertSwiftCodeQuery = `
 INSERT INTO swift_codes (country_iso2_code, swift_code,
                          bank_name, address,
-                         country_name, is_headquarter)
-VALUES ($1, $2, $3, $4, $5, $6)
+                         country_name, is_headquarter,
+                         code_type, town_name, time_zone)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (swift_code) DO NOTHING
+`
+
+	importSwiftCodeQuery = `
+INSERT INTO swift_codes (country_iso2_code, swift_code,
+                         bank_name, address,
+                         country_name, is_headquarter,
+                         code_type, town_name, time_zone,
+                         import_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 ON CONFLICT (swift_code) DO NOTHING
 `
 
@@ -30,20 +41,23 @@ DELETE FROM swift_codes WHERE swift_code=$1
 `
 
 	fetchSwiftCodesByCountryQuery = `
-SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name
+SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+       code_type, town_name, time_zone
 FROM swift_codes
 WHERE country_iso2_code = $1
 `
 
 	fetchSwiftCodeQuery = `
-SELECT swift_code, address, country_name, is_headquarter, country_iso2_code, bank_name
+SELECT swift_code, address, country_name, is_headquarter, country_iso2_code, bank_name,
+       code_type, town_name, time_zone
 FROM swift_codes
 WHERE swift_code = $1
 `
 
 	fetchBranchQuery = `
 SELECT swift_codes.swift_code, swift_codes.address, swift_codes.is_headquarter,
-	   swift_codes.country_iso2_code, swift_codes.bank_name
+	   swift_codes.country_iso2_code, swift_codes.bank_name,
+	   swift_codes.code_type, swift_codes.town_name, swift_codes.time_zone
 FROM branches
 JOIN swift_codes ON swift_codes.swift_code = branches.swift_code
 WHERE branches.headquarter = $1
@@ -73,6 +87,9 @@ func TestInsertRowsToDatabase(t *testing.T) {
 				Address:       "Krakow",
 				Country:       "Poland",
 				IsHeadquarter: false,
+				CodeType:      "BIC11",
+				TownName:      "KRAKOW",
+				TimeZone:      "Europe/Warsaw",
 			},
 			{
 				ISO2Code:      "PL",
@@ -87,8 +104,9 @@ func TestInsertRowsToDatabase(t *testing.T) {
 		mock.ExpectBegin()
 
 		for _, r := range records {
-			mock.ExpectExec(insertSwiftCodeQuery).
-				WithArgs(r.ISO2Code, r.SwiftCode, r.BankName, r.Address, r.Country, r.IsHeadquarter).
+			mock.ExpectExec(importSwiftCodeQuery).
+				WithArgs(r.ISO2Code, r.SwiftCode, r.BankName, r.Address, r.Country, r.IsHeadquarter,
+					r.CodeType, r.TownName, r.TimeZone, nil).
 				WillReturnResult(sqlmock.NewResult(1, 1))
 		}
 
@@ -102,7 +120,7 @@ func TestInsertRowsToDatabase(t *testing.T) {
 
 		mock.ExpectCommit()
 
-		err = InsertRowsToDatabase(db, records)
+		err = InsertRowsToDatabase(t.Context(), db, records)
 		require.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -114,7 +132,7 @@ func TestInsertRowsToDatabase(t *testing.T) {
 
 		mock.ExpectBegin().WillReturnError(errors.New("begin failed"))
 
-		err = InsertRowsToDatabase(db, nil)
+		err = InsertRowsToDatabase(t.Context(), db, nil)
 		require.ErrorContains(t, err, "begin failed")
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -134,8 +152,9 @@ func TestInsertRowsToDatabase(t *testing.T) {
 		}
 
 		mock.ExpectBegin()
-		mock.ExpectExec(insertSwiftCodeQuery).
-			WithArgs(r.ISO2Code, r.SwiftCode, r.BankName, r.Address, r.Country, r.IsHeadquarter).
+		mock.ExpectExec(importSwiftCodeQuery).
+			WithArgs(r.ISO2Code, r.SwiftCode, r.BankName, r.Address, r.Country, r.IsHeadquarter,
+				r.CodeType, r.TownName, r.TimeZone, nil).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		mock.ExpectQuery(selectExists).
@@ -146,7 +165,7 @@ func TestInsertRowsToDatabase(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
-		err = InsertRowsToDatabase(db, []parser.SwiftRecord{r})
+		err = InsertRowsToDatabase(t.Context(), db, []parser.SwiftRecord{r})
 		require.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -163,18 +182,23 @@ func TestFetchSwiftCode(t *testing.T) {
 		mock.ExpectQuery(fetchSwiftCodeQuery).WithArgs(hqCode).
 			WillReturnRows(sqlmock.NewRows([]string{
 				"swift_code", "address", "country_name", "is_headquarter", "country_iso2_code", "bank_name",
-			}).AddRow(hqCode, "Warsaw", "Poland", true, "PL", "PKO"))
+				"code_type", "town_name", "time_zone",
+			}).AddRow(hqCode, "Warsaw", "Poland", true, "PL", "PKO", "BIC11", "WARSZAWA", "Europe/Warsaw"))
 
 		mock.ExpectQuery(fetchBranchQuery).WithArgs(hqCode).
 			WillReturnRows(sqlmock.NewRows([]string{
 				"swift_code", "address", "is_headquarter", "country_iso2_code", "bank_name",
-			}).AddRow("PKOPPLPW002", "Krakow", false, "PL", "PKO"))
+				"code_type", "town_name", "time_zone",
+			}).AddRow("PKOPPLPW002", "Krakow", false, "PL", "PKO", "BIC11", "KRAKOW", "Europe/Warsaw"))
 
-		hq, branches, err := FetchSwiftCode(db, hqCode)
+		hq, branches, err := FetchSwiftCode(t.Context(), db, hqCode)
 		require.NoError(t, err)
 		require.Equal(t, hq.SwiftCode, hqCode)
+		require.Equal(t, "WARSZAWA", hq.TownName)
+		require.Equal(t, "Europe/Warsaw", hq.TimeZone)
 		require.Len(t, branches, 1)
 		require.Equal(t, branches[0].SwiftCode, "PKOPPLPW002")
+		require.Equal(t, "KRAKOW", branches[0].TownName)
 	})
 
 	t.Run("returns single branch without branches", func(t *testing.T) {
@@ -187,9 +211,10 @@ func TestFetchSwiftCode(t *testing.T) {
 			WithArgs(swift).
 			WillReturnRows(sqlmock.NewRows([]string{
 				"swift_code", "address", "country_name", "is_headquarter", "country_iso2_code", "bank_name",
-			}).AddRow(swift, "Krakow", "Poland", false, "PL", "PKO"))
+				"code_type", "town_name", "time_zone",
+			}).AddRow(swift, "Krakow", "Poland", false, "PL", "PKO", "BIC11", "KRAKOW", "Europe/Warsaw"))
 
-		hq, branches, err := FetchSwiftCode(db, swift)
+		hq, branches, err := FetchSwiftCode(t.Context(), db, swift)
 		require.NoError(t, err)
 		require.Equal(t, hq.SwiftCode, swift)
 		require.Nil(t, branches)
@@ -204,8 +229,8 @@ func TestFetchSwiftCode(t *testing.T) {
 			WithArgs("UNKNOWN").
 			WillReturnRows(sqlmock.NewRows([]string{}))
 
-		_, _, err = FetchSwiftCode(db, "UNKNOWN")
-		require.ErrorIs(t, err, sql.ErrNoRows)
+		_, _, err = FetchSwiftCode(t.Context(), db, "UNKNOWN")
+		require.ErrorIs(t, err, ErrNotFound)
 	})
 
 	t.Run("db error on swift fetch", func(t *testing.T) {
@@ -217,7 +242,7 @@ func TestFetchSwiftCode(t *testing.T) {
 			WithArgs("ERR").
 			WillReturnError(errors.New("db error"))
 
-		_, _, err = FetchSwiftCode(db, "ERR")
+		_, _, err = FetchSwiftCode(t.Context(), db, "ERR")
 		require.ErrorContains(t, err, "db error")
 	})
 }
@@ -233,10 +258,11 @@ func TestFetchSwiftCodesByCountry(t *testing.T) {
 			WithArgs(country).
 			WillReturnRows(sqlmock.NewRows([]string{
 				"address", "bank_name", "country_iso2_code", "is_headquarter", "swift_code", "country_name",
-			}).AddRow("Warsaw", "PKO", "PL", true, "PKOPPLPWXXX", "Poland").
-				AddRow("Krakow", "PKO", "PL", false, "PKOPPLPW002", "Poland"))
+				"code_type", "town_name", "time_zone",
+			}).AddRow("Warsaw", "PKO", "PL", true, "PKOPPLPWXXX", "Poland", "BIC11", "WARSZAWA", "Europe/Warsaw").
+				AddRow("Krakow", "PKO", "PL", false, "PKOPPLPW002", "Poland", "BIC11", "KRAKOW", "Europe/Warsaw"))
 
-		result, err := FetchSwiftCodesByCountry(db, country)
+		result, err := FetchSwiftCodesByCountry(t.Context(), db, country)
 		require.NoError(t, err)
 		require.Len(t, result, 2)
 	})
@@ -250,7 +276,7 @@ func TestFetchSwiftCodesByCountry(t *testing.T) {
 			WithArgs("XX").
 			WillReturnRows(sqlmock.NewRows([]string{}))
 
-		result, err := FetchSwiftCodesByCountry(db, "XX")
+		result, err := FetchSwiftCodesByCountry(t.Context(), db, "XX")
 		require.NoError(t, err)
 		require.Empty(t, result)
 	})
@@ -264,7 +290,7 @@ func TestFetchSwiftCodesByCountry(t *testing.T) {
 			WithArgs("ERR").
 			WillReturnError(errors.New("db error"))
 
-		_, err = FetchSwiftCodesByCountry(db, "ERR")
+		_, err = FetchSwiftCodesByCountry(t.Context(), db, "ERR")
 		require.ErrorContains(t, err, "db error")
 	})
 }
@@ -292,6 +318,9 @@ func TestInsertNewSwiftCode(t *testing.T) {
 				swift.Address,
 				swift.CountryName,
 				swift.IsHeadquarter,
+				swift.CodeType,
+				swift.TownName,
+				swift.TimeZone,
 			).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -299,7 +328,7 @@ func TestInsertNewSwiftCode(t *testing.T) {
 			WithArgs(swift.SwiftCode, swift.SwiftCode[:8]+"%").
 			WillReturnResult(sqlmock.NewResult(0, 0))
 
-		err = InsertNewSwiftCode(db, swift)
+		err = InsertNewSwiftCode(t.Context(), db, swift)
 		require.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -326,6 +355,9 @@ func TestInsertNewSwiftCode(t *testing.T) {
 				swift.Address,
 				swift.CountryName,
 				swift.IsHeadquarter,
+				swift.CodeType,
+				swift.TownName,
+				swift.TimeZone,
 			).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -333,7 +365,7 @@ func TestInsertNewSwiftCode(t *testing.T) {
 			WithArgs(swift.SwiftCode, swift.SwiftCode[:8]+"%").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err = InsertNewSwiftCode(db, swift)
+		err = InsertNewSwiftCode(t.Context(), db, swift)
 		require.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -359,7 +391,10 @@ func TestInsertNewSwiftCode(t *testing.T) {
 				swift.BankName,
 				swift.Address,
 				swift.CountryName,
-				swift.IsHeadquarter).WillReturnResult(sqlmock.NewResult(1, 1))
+				swift.IsHeadquarter,
+				swift.CodeType,
+				swift.TownName,
+				swift.TimeZone).WillReturnResult(sqlmock.NewResult(1, 1))
 
 		mock.ExpectQuery(selectExists).
 			WithArgs(hq).
@@ -368,7 +403,7 @@ func TestInsertNewSwiftCode(t *testing.T) {
 		mock.ExpectExec(insertBranchQuery).
 			WithArgs(swift.SwiftCode, hq).WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err = InsertNewSwiftCode(db, swift)
+		err = InsertNewSwiftCode(t.Context(), db, swift)
 		require.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -394,7 +429,10 @@ func TestInsertNewSwiftCode(t *testing.T) {
 				swift.BankName,
 				swift.Address,
 				swift.CountryName,
-				swift.IsHeadquarter).WillReturnResult(sqlmock.NewResult(1, 1))
+				swift.IsHeadquarter,
+				swift.CodeType,
+				swift.TownName,
+				swift.TimeZone).WillReturnResult(sqlmock.NewResult(1, 1))
 
 		mock.ExpectQuery(selectExists).
 			WithArgs(hq).
@@ -403,7 +441,7 @@ func TestInsertNewSwiftCode(t *testing.T) {
 		mock.ExpectExec(insertBranchQuery).
 			WithArgs(swift.SwiftCode, nil).WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err = InsertNewSwiftCode(db, swift)
+		err = InsertNewSwiftCode(t.Context(), db, swift)
 		require.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -430,14 +468,50 @@ func TestInsertNewSwiftCode(t *testing.T) {
 				swift.Address,
 				swift.CountryName,
 				swift.IsHeadquarter,
+				swift.CodeType,
+				swift.TownName,
+				swift.TimeZone,
 			).
 			WillReturnError(errors.New("invalid swift code"))
 
-		err = InsertNewSwiftCode(db, swift)
+		err = InsertNewSwiftCode(t.Context(), db, swift)
 		require.Error(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("duplicate swift code", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		swift := model.SwiftCode{
+			CountryISO2:   "PL",
+			SwiftCode:     "PKOPPLPWXXX",
+			BankName:      "PKO",
+			Address:       "Warsaw",
+			CountryName:   "Poland",
+			IsHeadquarter: true,
+		}
+
+		mock.ExpectExec(insertSwiftCodeQuery).
+			WithArgs(
+				swift.CountryISO2,
+				swift.SwiftCode,
+				swift.BankName,
+				swift.Address,
+				swift.CountryName,
+				swift.IsHeadquarter,
+				swift.CodeType,
+				swift.TownName,
+				swift.TimeZone,
+			).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = InsertNewSwiftCode(t.Context(), db, swift)
+		require.ErrorIs(t, err, ErrConflict)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("db returns error", func(t *testing.T) {
 		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 		require.NoError(t, err)
@@ -460,10 +534,13 @@ func TestInsertNewSwiftCode(t *testing.T) {
 				swift.Address,
 				swift.CountryName,
 				swift.IsHeadquarter,
+				swift.CodeType,
+				swift.TownName,
+				swift.TimeZone,
 			).
 			WillReturnError(errors.New("connection lost"))
 
-		err = InsertNewSwiftCode(db, swift)
+		err = InsertNewSwiftCode(t.Context(), db, swift)
 		require.Error(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -471,6 +548,28 @@ func TestInsertNewSwiftCode(t *testing.T) {
 }
 
 func TestDeleteSwiftCode(t *testing.T) {
+	t.Run("unknown swift code", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectExec(deleteSwiftCodeQuery).
+			WithArgs("UNKNOWNXXXX").WillReturnResult(sqlmock.NewResult(0, 0))
+		err = DeleteSwiftCode(t.Context(), db, "UNKNOWNXXXX")
+		require.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("headquarter with branches", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+		mock.ExpectExec(deleteSwiftCodeQuery).
+			WithArgs("PKOPPLPWXXX").WillReturnError(&pq.Error{Code: "23503"})
+		err = DeleteSwiftCode(t.Context(), db, "PKOPPLPWXXX")
+		require.ErrorIs(t, err, ErrHasBranches)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("valid removal", func(t *testing.T) {
 		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 		require.NoError(t, err)
@@ -478,7 +577,7 @@ func TestDeleteSwiftCode(t *testing.T) {
 		swiftCode := "PKOPPLPWXXX"
 		mock.ExpectExec(deleteSwiftCodeQuery).
 			WithArgs(swiftCode).WillReturnResult(sqlmock.NewResult(1, 1))
-		err = DeleteSwiftCode(db, swiftCode)
+		err = DeleteSwiftCode(t.Context(), db, swiftCode)
 		require.NoError(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -489,7 +588,7 @@ func TestDeleteSwiftCode(t *testing.T) {
 		defer db.Close()
 		mock.ExpectExec(deleteSwiftCodeQuery).
 			WillReturnError(errors.New("invalid swift code"))
-		err = DeleteSwiftCode(db, "")
+		err = DeleteSwiftCode(t.Context(), db, "")
 		require.Error(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
@@ -500,8 +599,112 @@ func TestDeleteSwiftCode(t *testing.T) {
 		defer db.Close()
 		mock.ExpectExec(deleteSwiftCodeQuery).
 			WillReturnError(errors.New("connection lost"))
-		err = DeleteSwiftCode(db, "PKOPPLPWXXX")
+		err = DeleteSwiftCode(t.Context(), db, "PKOPPLPWXXX")
 		require.Error(t, err)
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+var (
+	fetchForUpdateQuery = `
+SELECT swift_code, address, country_name, is_headquarter, country_iso2_code, bank_name,
+       code_type, town_name, time_zone
+FROM swift_codes
+WHERE swift_code = $1
+FOR UPDATE
+`
+
+	updateSwiftCodeQuery = `
+UPDATE swift_codes
+SET country_iso2_code = $2, bank_name = $3, address = $4, country_name = $5,
+    is_headquarter = $6, code_type = $7, town_name = $8, time_zone = $9,
+    import_id = NULL
+WHERE swift_code = $1
+`
+)
+
+func currentRow(code string, isHeadquarter bool) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"swift_code", "address", "country_name", "is_headquarter", "country_iso2_code", "bank_name",
+		"code_type", "town_name", "time_zone",
+	}).AddRow(code, "Warsaw", "Poland", isHeadquarter, "PL", "PKO", "BIC11", "WARSZAWA", "Europe/Warsaw")
+}
+
+func TestUpdateSwiftCode(t *testing.T) {
+	t.Run("full replace keeps branch links", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		swift := model.SwiftCode{
+			SwiftCode: "PKOPPLPWXXX", CountryISO2: "PL", BankName: "PKO BP", Address: "Pulawska 15",
+			CountryName: "Poland", IsHeadquarter: true, CodeType: "BIC11", TownName: "WARSZAWA", TimeZone: "Europe/Warsaw",
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(fetchForUpdateQuery).WithArgs(swift.SwiftCode).WillReturnRows(currentRow(swift.SwiftCode, true))
+		mock.ExpectExec(updateSwiftCodeQuery).
+			WithArgs(swift.SwiftCode, swift.CountryISO2, swift.BankName, swift.Address, swift.CountryName,
+				swift.IsHeadquarter, swift.CodeType, swift.TownName, swift.TimeZone).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, UpdateSwiftCode(t.Context(), db, swift))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(fetchForUpdateQuery).WithArgs("UNKNOWNXXXX").WillReturnRows(sqlmock.NewRows([]string{}))
+		mock.ExpectRollback()
+
+		err = UpdateSwiftCode(t.Context(), db, model.SwiftCode{SwiftCode: "UNKNOWNXXXX"})
+		require.ErrorIs(t, err, ErrNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPatchSwiftCode(t *testing.T) {
+	t.Run("partial update", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		address := "Pulawska 15"
+		mock.ExpectBegin()
+		mock.ExpectQuery(fetchForUpdateQuery).WithArgs("PKOPPLPW002").WillReturnRows(currentRow("PKOPPLPW002", false))
+		mock.ExpectExec(updateSwiftCodeQuery).
+			WithArgs("PKOPPLPW002", "PL", "PKO", address, "Poland", false, "BIC11", "WARSZAWA", "Europe/Warsaw").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		updated, err := PatchSwiftCode(t.Context(), db, "PKOPPLPW002", model.SwiftCodePatch{Address: &address})
+		require.NoError(t, err)
+		require.Equal(t, address, updated.Address)
+		require.Equal(t, "PKO", updated.BankName)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("isHeadquarter stays with the code", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		isHeadquarter := true
+		mock.ExpectBegin()
+		mock.ExpectQuery(fetchForUpdateQuery).WithArgs("PKOPPLPW002").WillReturnRows(currentRow("PKOPPLPW002", false))
+		mock.ExpectExec(updateSwiftCodeQuery).
+			WithArgs("PKOPPLPW002", "PL", "PKO", "Warsaw", "Poland", false, "BIC11", "WARSZAWA", "Europe/Warsaw").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		updated, err := PatchSwiftCode(t.Context(), db, "PKOPPLPW002", model.SwiftCodePatch{IsHeadquarter: &isHeadquarter})
+		require.NoError(t, err)
+		require.False(t, updated.IsHeadquarter)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("already exists")
+	ErrHasBranches = errors.New("headquarter still has branches")
+)
+
+const (
+	foreignKeyViolation = "23503"
+	undefinedTable      = "42P01"
+)
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation
+}
+
+// isUndefinedTable reports whether a query failed because its table does not
+// exist. SQLite has no error code for this, only the "no such table" message.
+func isUndefinedTable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == undefinedTable
+	}
+	return err != nil && strings.Contains(err.Error(), "no such table")
+}
+
+// ContextError wraps err with ctx.Err() once ctx is done. Drivers report a
+// cancelled statement in their own words (lib/pq returns "canceling statement
+// due to user request"), so without this callers could not tell a timeout
+// from any other failure.
+func ContextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ctx.Err(), err)
+}
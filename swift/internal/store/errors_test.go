@@ -0,0 +1,25 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextError(t *testing.T) {
+	driverErr := errors.New("pq: canceling statement due to user request")
+
+	assert.Equal(t, driverErr, ContextError(t.Context(), driverErr), "a live context leaves the error alone")
+	assert.NoError(t, ContextError(t.Context(), nil))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	assert.NoError(t, ContextError(ctx, nil))
+	assert.Equal(t, context.Canceled, ContextError(ctx, context.Canceled))
+
+	err := ContextError(ctx, driverErr)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), driverErr.Error())
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mbartnicki80/swift/internal/model"
+)
+
+const exportFetchSize = 1000
+
+type ExportQuery struct {
+	ListFilter
+	Sort SortField
+	Desc bool
+}
+
+// StreamSwiftCodes walks the matching rows through a server-side cursor, so
+// the whole directory can be exported without holding it in memory.
+func StreamSwiftCodes(ctx context.Context, db *sql.DB, q ExportQuery, fn func(model.SwiftCode) error) error {
+	if q.Sort == "" {
+		q.Sort = SortSwiftCode
+	}
+	column, ok := sortColumns[q.Sort]
+	if !ok {
+		return fmt.Errorf("unknown sort field %q", q.Sort)
+	}
+	direction := "ASC"
+	if q.Desc {
+		direction = "DESC"
+	}
+
+	where := ""
+	conditions, args := q.conditions()
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	declareQuery := fmt.Sprintf(`
+		DECLARE export_cursor NO SCROLL CURSOR FOR
+		SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+		       code_type, town_name, time_zone
+		FROM swift_codes
+		%s
+		ORDER BY %s %s, swift_code %s
+		`, where, column, direction, direction)
+	if _, err := tx.ExecContext(ctx, declareQuery, args...); err != nil {
+		return err
+	}
+
+	fetchQuery := fmt.Sprintf("FETCH FORWARD %d FROM export_cursor", exportFetchSize)
+	for {
+		fetched, err := fetchExportBatch(ctx, tx, fetchQuery, fn)
+		if err != nil {
+			return err
+		}
+		if fetched < exportFetchSize {
+			break
+		}
+	}
+	return tx.Commit()
+}
+
+func fetchExportBatch(ctx context.Context, tx *sql.Tx, fetchQuery string, fn func(model.SwiftCode) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, fetchQuery)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var code model.SwiftCode
+		err := rows.Scan(&code.Address, &code.BankName, &code.CountryISO2, &code.IsHeadquarter, &code.SwiftCode, &code.CountryName,
+			&code.CodeType, &code.TownName, &code.TimeZone)
+		if err != nil {
+			return fetched, err
+		}
+		fetched++
+		if err := ctx.Err(); err != nil {
+			return fetched, err
+		}
+		if err := fn(code); err != nil {
+			return fetched, err
+		}
+	}
+	return fetched, rows.Err()
+}
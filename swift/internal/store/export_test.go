@@ -0,0 +1,54 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const declareExportQuery = `
+DECLARE export_cursor NO SCROLL CURSOR FOR
+SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+       code_type, town_name, time_zone
+FROM swift_codes
+WHERE country_iso2_code = $1 AND is_headquarter = $2
+ORDER BY bank_name DESC, swift_code DESC
+`
+
+func TestStreamSwiftCodes(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	fetchQuery := fmt.Sprintf("FETCH FORWARD %d FROM export_cursor", exportFetchSize)
+	full := sqlmock.NewRows(pageColumns)
+	for i := 0; i < exportFetchSize; i++ {
+		full.AddRow("", "PKO", "PL", true, fmt.Sprintf("PKOPPL%05d", i), "POLAND", "", "", "")
+	}
+
+	isHeadquarter := true
+	mock.ExpectBegin()
+	mock.ExpectExec(declareExportQuery).WithArgs("PL", true).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(fetchQuery).WillReturnRows(full)
+	mock.ExpectQuery(fetchQuery).WillReturnRows(sqlmock.NewRows(pageColumns).
+		AddRow("", "MBANK", "PL", true, "BREXPLPWXXX", "POLAND", "", "", ""))
+	mock.ExpectCommit()
+
+	var streamed []string
+	err = StreamSwiftCodes(t.Context(), db, ExportQuery{
+		ListFilter: ListFilter{CountryISO2: "PL", IsHeadquarter: &isHeadquarter},
+		Sort:       SortBankName,
+		Desc:       true,
+	}, func(code model.SwiftCode) error {
+		streamed = append(streamed, code.SwiftCode)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, streamed, exportFetchSize+1)
+	assert.Equal(t, "BREXPLPWXXX", streamed[exportFetchSize])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/mbartnicki80/swift/internal/model"
+)
+
+const (
+	ImportRunning   = "running"
+	ImportSucceeded = "succeeded"
+	ImportFailed    = "failed"
+)
+
+func StartImport(ctx context.Context, db *sql.DB, imp model.Import) (int64, error) {
+	startImportQuery := `
+		INSERT INTO imports (source_filename, checksum, format, mode, operator, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	var id int64
+	err := db.QueryRowContext(ctx, startImportQuery, imp.SourceFilename, imp.Checksum, imp.Format, imp.Mode,
+		imp.Operator, ImportRunning).Scan(&id)
+	return id, err
+}
+
+func FinishImport(ctx context.Context, db *sql.DB, id int64, status string, total, accepted, rejected int) error {
+	finishImportQuery := `
+		UPDATE imports
+		SET status = $2, total_rows = $3, accepted_rows = $4, rejected_rows = $5, finished_at = now()
+		WHERE id = $1
+	`
+	_, err := db.ExecContext(ctx, finishImportQuery, id, status, total, accepted, rejected)
+	return err
+}
+
+const importColumns = `
+	id, source_filename, checksum, format, mode, operator, status,
+	total_rows, accepted_rows, rejected_rows, started_at, finished_at
+`
+
+func scanImport(row interface{ Scan(...any) error }) (model.Import, error) {
+	var imp model.Import
+	err := row.Scan(&imp.ID, &imp.SourceFilename, &imp.Checksum, &imp.Format, &imp.Mode, &imp.Operator,
+		&imp.Status, &imp.TotalRows, &imp.AcceptedRows, &imp.RejectedRows, &imp.StartedAt, &imp.FinishedAt)
+	return imp, err
+}
+
+func FetchImport(ctx context.Context, db *sql.DB, id int64) (model.Import, error) {
+	imp, err := scanImport(db.QueryRowContext(ctx, "SELECT"+importColumns+"FROM imports WHERE id = $1", id))
+	return imp, notFound(err)
+}
+
+func FetchImports(ctx context.Context, db *sql.DB) ([]model.Import, error) {
+	rows, err := db.QueryContext(ctx, "SELECT"+importColumns+"FROM imports ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	imports := []model.Import{}
+	for rows.Next() {
+		imp, err := scanImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, imp)
+	}
+	return imports, rows.Err()
+}
+
+func FetchProvenance(ctx context.Context, db *sql.DB, swiftCodes []string) (map[string]model.Provenance, error) {
+	fetchProvenanceQuery := `
+		SELECT swift_codes.swift_code, imports.id, imports.source_filename, imports.checksum,
+		       imports.operator, imports.started_at, imports.finished_at
+		FROM swift_codes
+		JOIN imports ON imports.id = swift_codes.import_id
+		WHERE swift_codes.swift_code = ANY($1)
+	`
+	rows, err := db.QueryContext(ctx, fetchProvenanceQuery, pq.Array(swiftCodes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]model.Provenance)
+	for rows.Next() {
+		var code string
+		var p model.Provenance
+		err := rows.Scan(&code, &p.ImportID, &p.SourceFilename, &p.Checksum, &p.Operator, &p.ImportedAt, &p.FinishedAt)
+		if err != nil {
+			return nil, err
+		}
+		result[code] = p
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var (
+	startImportQuery = `
+INSERT INTO imports (source_filename, checksum, format, mode, operator, status)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id
+`
+
+	finishImportQuery = `
+UPDATE imports
+SET status = $2, total_rows = $3, accepted_rows = $4, rejected_rows = $5, finished_at = now()
+WHERE id = $1
+`
+
+	fetchProvenanceQuery = `
+SELECT swift_codes.swift_code, imports.id, imports.source_filename, imports.checksum,
+       imports.operator, imports.started_at, imports.finished_at
+FROM swift_codes
+JOIN imports ON imports.id = swift_codes.import_id
+WHERE swift_codes.swift_code = ANY($1)
+`
+)
+
+func TestImportHistory(t *testing.T) {
+	t.Run("start and finish", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(startImportQuery).
+			WithArgs("swift_codes.xlsx", "abc123", "xlsx", "sync", "ops", ImportRunning).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectExec(finishImportQuery).
+			WithArgs(int64(7), ImportSucceeded, 10, 9, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		id, err := StartImport(t.Context(), db, model.Import{
+			SourceFilename: "swift_codes.xlsx",
+			Checksum:       "abc123",
+			Format:         "xlsx",
+			Mode:           "sync",
+			Operator:       "ops",
+		})
+		require.NoError(t, err)
+		require.Equal(t, int64(7), id)
+
+		require.NoError(t, FinishImport(t.Context(), db, id, ImportSucceeded, 10, 9, 1))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fetch provenance", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		started := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(fetchProvenanceQuery).
+			WithArgs(pq.Array([]string{"PKOPPLPWXXX", "PKOPPLPW002"})).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"swift_code", "id", "source_filename", "checksum", "operator", "started_at", "finished_at",
+			}).AddRow("PKOPPLPWXXX", 7, "swift_codes.xlsx", "abc123", "ops", started, started.Add(time.Minute)))
+
+		sources, err := FetchProvenance(t.Context(), db, []string{"PKOPPLPWXXX", "PKOPPLPW002"})
+		require.NoError(t, err)
+		require.Len(t, sources, 1)
+		require.Equal(t, int64(7), sources["PKOPPLPWXXX"].ImportID)
+		require.Equal(t, "swift_codes.xlsx", sources["PKOPPLPWXXX"].SourceFilename)
+		require.Equal(t, started, sources["PKOPPLPWXXX"].ImportedAt)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
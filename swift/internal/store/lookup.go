@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/mbartnicki80/swift/internal/model"
+)
+
+type LookupEntry struct {
+	Code     model.SwiftCode
+	Branches []model.SwiftCode
+}
+
+// LookupSwiftCodes resolves many codes with one query that returns the
+// requested rows together with the branches of every requested headquarter.
+// The two halves are a UNION ALL rather than one OR so that each can use its
+// index (the primary key and idx_branches_headquarter); the second skips
+// branches the first already returned. Found entries keep the order of
+// codes; duplicates are returned once.
+func LookupSwiftCodes(ctx context.Context, db *sql.DB, codes []string) ([]LookupEntry, []string, error) {
+	lookupQuery := `
+		SELECT s.swift_code, s.address, s.country_name, s.is_headquarter, s.country_iso2_code, s.bank_name,
+		       s.code_type, s.town_name, s.time_zone, b.headquarter
+		FROM swift_codes s
+		LEFT JOIN branches b ON b.swift_code = s.swift_code
+		WHERE s.swift_code = ANY($1)
+		UNION ALL
+		SELECT s.swift_code, s.address, s.country_name, s.is_headquarter, s.country_iso2_code, s.bank_name,
+		       s.code_type, s.town_name, s.time_zone, b.headquarter
+		FROM branches b
+		JOIN swift_codes s ON s.swift_code = b.swift_code
+		WHERE b.headquarter = ANY($1) AND s.swift_code <> ALL($1)
+		ORDER BY swift_code
+	`
+	rows, err := db.QueryContext(ctx, lookupQuery, pq.Array(codes))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	result := NewLookupResult(codes)
+	for rows.Next() {
+		var code model.SwiftCode
+		var headquarter sql.NullString
+		err := rows.Scan(&code.SwiftCode, &code.Address, &code.CountryName, &code.IsHeadquarter, &code.CountryISO2, &code.BankName,
+			&code.CodeType, &code.TownName, &code.TimeZone, &headquarter)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Add(code, headquarter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	entries, notFound := result.Entries()
+	return entries, notFound, nil
+}
+
+// LookupResult gathers the rows of a batch lookup query: the requested
+// codes, each with its headquarter if it is a branch, and the branches of the
+// requested headquarters.
+type LookupResult struct {
+	codes     []string
+	requested map[string]bool
+	found     map[string]model.SwiftCode
+	branches  map[string][]model.SwiftCode
+}
+
+func NewLookupResult(codes []string) *LookupResult {
+	requested := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		requested[code] = true
+	}
+	return &LookupResult{
+		codes:     codes,
+		requested: requested,
+		found:     make(map[string]model.SwiftCode),
+		branches:  make(map[string][]model.SwiftCode),
+	}
+}
+
+// Add records one row, in the order the branches should be listed.
+func (l *LookupResult) Add(code model.SwiftCode, headquarter sql.NullString) {
+	if l.requested[code.SwiftCode] {
+		l.found[code.SwiftCode] = code
+	}
+	if headquarter.Valid && l.requested[headquarter.String] {
+		l.branches[headquarter.String] = append(l.branches[headquarter.String], code)
+	}
+}
+
+func (l *LookupResult) Entries() ([]LookupEntry, []string) {
+	return LookupEntries(l.codes,
+		func(swiftCode string) (model.SwiftCode, bool) {
+			code, ok := l.found[swiftCode]
+			return code, ok
+		},
+		func(headquarter string) []model.SwiftCode { return l.branches[headquarter] })
+}
+
+// LookupEntries answers a batch lookup from find and branchesOf: found
+// entries keep the order of codes and duplicates are returned once, a
+// headquarter always carries a (possibly empty) branch list, and the codes
+// find does not know are returned as not found.
+func LookupEntries(codes []string, find func(string) (model.SwiftCode, bool), branchesOf func(string) []model.SwiftCode) ([]LookupEntry, []string) {
+	entries := []LookupEntry{}
+	notFound := []string{}
+	seen := make(map[string]bool, len(codes))
+	for _, swiftCode := range codes {
+		if seen[swiftCode] {
+			continue
+		}
+		seen[swiftCode] = true
+
+		code, ok := find(swiftCode)
+		if !ok {
+			notFound = append(notFound, swiftCode)
+			continue
+		}
+		entry := LookupEntry{Code: code}
+		if code.IsHeadquarter {
+			entry.Branches = branchesOf(swiftCode)
+			if entry.Branches == nil {
+				entry.Branches = []model.SwiftCode{}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, notFound
+}
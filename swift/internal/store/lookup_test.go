@@ -0,0 +1,62 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lookupQuery = `
+SELECT s.swift_code, s.address, s.country_name, s.is_headquarter, s.country_iso2_code, s.bank_name,
+       s.code_type, s.town_name, s.time_zone, b.headquarter
+FROM swift_codes s
+LEFT JOIN branches b ON b.swift_code = s.swift_code
+WHERE s.swift_code = ANY($1)
+UNION ALL
+SELECT s.swift_code, s.address, s.country_name, s.is_headquarter, s.country_iso2_code, s.bank_name,
+       s.code_type, s.town_name, s.time_zone, b.headquarter
+FROM branches b
+JOIN swift_codes s ON s.swift_code = b.swift_code
+WHERE b.headquarter = ANY($1) AND s.swift_code <> ALL($1)
+ORDER BY swift_code
+`
+
+var lookupColumns = []string{
+	"swift_code", "address", "country_name", "is_headquarter", "country_iso2_code", "bank_name",
+	"code_type", "town_name", "time_zone", "headquarter",
+}
+
+func TestLookupSwiftCodes(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	codes := []string{"PKOPPLPWXXX", "MISSINGXXXX", "PKOPPLPW002", "BREXPLPWXXX", "PKOPPLPWXXX"}
+	mock.ExpectQuery(lookupQuery).
+		WithArgs(pq.Array(codes)).
+		WillReturnRows(sqlmock.NewRows(lookupColumns).
+			AddRow("BREXPLPWXXX", "Warsaw", "POLAND", true, "PL", "MBANK", "BIC11", "WARSZAWA", "Europe/Warsaw", nil).
+			AddRow("PKOPPLPW002", "Krakow", "POLAND", false, "PL", "PKO", "BIC11", "KRAKOW", "Europe/Warsaw", "PKOPPLPWXXX").
+			AddRow("PKOPPLPW003", "Gdansk", "POLAND", false, "PL", "PKO", "BIC11", "GDANSK", "Europe/Warsaw", "PKOPPLPWXXX").
+			AddRow("PKOPPLPWXXX", "Warsaw", "POLAND", true, "PL", "PKO", "BIC11", "WARSZAWA", "Europe/Warsaw", nil))
+
+	entries, notFound, err := LookupSwiftCodes(t.Context(), db, codes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MISSINGXXXX"}, notFound)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "PKOPPLPWXXX", entries[0].Code.SwiftCode)
+	require.Len(t, entries[0].Branches, 2)
+	assert.Equal(t, "PKOPPLPW002", entries[0].Branches[0].SwiftCode)
+
+	assert.Equal(t, "PKOPPLPW002", entries[1].Code.SwiftCode)
+	assert.Nil(t, entries[1].Branches)
+
+	assert.Equal(t, "BREXPLPWXXX", entries[2].Code.SwiftCode)
+	assert.NotNil(t, entries[2].Branches)
+	assert.Empty(t, entries[2].Branches)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/mbartnicki80/swift/internal/model"
+)
+
+const (
+	DefaultMatchThreshold = 0.3
+	DefaultMatchLimit     = 10
+	MaxMatchLimit         = 100
+)
+
+type MatchQuery struct {
+	Name        string
+	CountryISO2 string
+	Threshold   float64
+	Limit       int
+}
+
+func (q MatchQuery) Normalize() MatchQuery {
+	if q.Threshold <= 0 || q.Threshold > 1 {
+		q.Threshold = DefaultMatchThreshold
+	}
+	if q.Limit <= 0 || q.Limit > MaxMatchLimit {
+		q.Limit = DefaultMatchLimit
+	}
+	return q
+}
+
+// MatchBankName returns codes whose bank name is trigram-similar to q.Name.
+// The threshold is set for the transaction only, so the % operator can use
+// the bank_name trigram index.
+func MatchBankName(ctx context.Context, db *sql.DB, q MatchQuery) ([]model.MatchCandidate, error) {
+	q = q.Normalize()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "SELECT set_config('pg_trgm.similarity_threshold', $1, true)", strconv.FormatFloat(q.Threshold, 'f', -1, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	matchQuery := `
+		SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+		       code_type, town_name, time_zone, similarity(bank_name, $1) AS score
+		FROM swift_codes
+		WHERE bank_name % $1 AND ($2 = '' OR country_iso2_code = $2)
+		ORDER BY score DESC, is_headquarter DESC, swift_code
+		LIMIT $3
+		`
+	rows, err := tx.QueryContext(ctx, matchQuery, q.Name, q.CountryISO2, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []model.MatchCandidate{}
+	for rows.Next() {
+		var candidate model.MatchCandidate
+		err := rows.Scan(&candidate.Address, &candidate.BankName, &candidate.CountryISO2, &candidate.IsHeadquarter, &candidate.SwiftCode.SwiftCode, &candidate.CountryName,
+			&candidate.CodeType, &candidate.TownName, &candidate.TimeZone, &candidate.Score)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return candidates, tx.Commit()
+}
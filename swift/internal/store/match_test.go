@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	setThresholdQuery = `SELECT set_config('pg_trgm.similarity_threshold', $1, true)`
+
+	matchBankNameQuery = `
+SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+       code_type, town_name, time_zone, similarity(bank_name, $1) AS score
+FROM swift_codes
+WHERE bank_name % $1 AND ($2 = '' OR country_iso2_code = $2)
+ORDER BY score DESC, is_headquarter DESC, swift_code
+LIMIT $3
+`
+)
+
+func TestMatchBankName(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(setThresholdQuery).WithArgs("0.3").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(matchBankNameQuery).
+		WithArgs("DEUTSCHE BK AG", "", DefaultMatchLimit).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"address", "bank_name", "country_iso2_code", "is_headquarter", "swift_code", "country_name",
+			"code_type", "town_name", "time_zone", "score",
+		}).AddRow("TAUNUSANLAGE 12", "DEUTSCHE BANK AG", "DE", true, "DEUTDEFFXXX", "GERMANY", "BIC11", "FRANKFURT AM MAIN", "Europe/Berlin", 0.65).
+			AddRow("", "DEUTSCHE BANK (SUISSE) SA", "CH", true, "DEUTCHGGXXX", "SWITZERLAND", "BIC11", "GENEVA", "Europe/Zurich", 0.41))
+	mock.ExpectCommit()
+
+	candidates, err := MatchBankName(t.Context(), db, MatchQuery{Name: "DEUTSCHE BK AG"})
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	assert.Equal(t, "DEUTDEFFXXX", candidates[0].SwiftCode.SwiftCode)
+	assert.Equal(t, 0.41, candidates[1].Score)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/mbartnicki80/swift/internal/store"
+)
+
+// Load inserts validated records the way an insert-mode import does: codes
+// that already exist are skipped, and branches loaded before their
+// headquarter are linked once it arrives. It returns how many were inserted.
+func (r *Repository) Load(records []parser.SwiftRecord) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inserted := 0
+	for _, record := range records {
+		if r.create(store.ModelFromRecord(record)) == nil {
+			inserted++
+		}
+	}
+	return inserted
+}
+
+// Imports is always empty: the in-memory backend has no imports table.
+func (r *Repository) Imports(ctx context.Context) ([]model.Import, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return []model.Import{}, nil
+}
+
+func (r *Repository) Import(ctx context.Context, id int64) (model.Import, error) {
+	if err := ctx.Err(); err != nil {
+		return model.Import{}, err
+	}
+	return model.Import{}, store.ErrNotFound
+}
+
+// DryRun reports what a sync import of the streamed records would change,
+// with the same rules as the Postgres dry run.
+func (r *Repository) DryRun(ctx context.Context, stream func(add func(parser.SwiftRecord) error) error) (store.Diff, error) {
+	incoming := make(map[string]model.SwiftCode)
+	err := stream(func(record parser.SwiftRecord) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, ok := incoming[record.SwiftCode]; !ok {
+			incoming[record.SwiftCode] = store.ModelFromRecord(record)
+		}
+		return nil
+	})
+	if err != nil {
+		return store.Diff{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	diff := store.Diff{
+		Inserted: []string{},
+		Updated:  []store.CodeChange{},
+		Deleted:  []string{},
+		Relinked: []store.Relink{},
+	}
+	for swiftCode, code := range incoming {
+		current, ok := r.codes[swiftCode]
+		if !ok {
+			diff.Inserted = append(diff.Inserted, swiftCode)
+			continue
+		}
+		change := store.NewCodeChange(swiftCode, store.FieldValues(current), store.FieldValues(code))
+		if len(change.Changes) > 0 || current.IsHeadquarter != code.IsHeadquarter {
+			diff.Updated = append(diff.Updated, change)
+		}
+	}
+	for swiftCode := range r.codes {
+		if _, ok := incoming[swiftCode]; !ok {
+			diff.Deleted = append(diff.Deleted, swiftCode)
+		}
+	}
+	for swiftCode, hq := range r.branches {
+		if _, ok := incoming[swiftCode]; !ok {
+			continue
+		}
+		var oldHeadquarter, newHeadquarter *string
+		if hq != "" {
+			oldHeadquarter = &hq
+		}
+		if candidate, ok := incoming[headquarterOf(swiftCode)]; ok && candidate.IsHeadquarter {
+			newHeadquarter = &candidate.SwiftCode
+		}
+		if headquarterName(oldHeadquarter) != headquarterName(newHeadquarter) {
+			diff.Relinked = append(diff.Relinked, store.Relink{SwiftCode: swiftCode, OldHeadquarter: oldHeadquarter, NewHeadquarter: newHeadquarter})
+		}
+	}
+
+	sort.Strings(diff.Inserted)
+	sort.Strings(diff.Deleted)
+	sort.Slice(diff.Updated, func(i, j int) bool { return diff.Updated[i].SwiftCode < diff.Updated[j].SwiftCode })
+	sort.Slice(diff.Relinked, func(i, j int) bool { return diff.Relinked[i].SwiftCode < diff.Relinked[j].SwiftCode })
+	return diff, nil
+}
+
+func headquarterName(hq *string) string {
+	if hq == nil {
+		return ""
+	}
+	return *hq
+}
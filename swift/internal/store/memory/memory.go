@@ -0,0 +1,221 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/store"
+)
+
+// Repository keeps swift_codes and branches in maps and mirrors the Postgres
+// behaviour: a branch row points at its prefix+XXX headquarter or holds an
+// empty (NULL) headquarter until one arrives, deleting a code cascades to its
+// own branch row, and deleting a headquarter that still has branches fails.
+type Repository struct {
+	mu       sync.RWMutex
+	codes    map[string]model.SwiftCode
+	branches map[string]string
+}
+
+var (
+	_ store.SwiftCodeRepository = (*Repository)(nil)
+	_ store.ImportRepository    = (*Repository)(nil)
+)
+
+func New() *Repository {
+	return &Repository{
+		codes:    make(map[string]model.SwiftCode),
+		branches: make(map[string]string),
+	}
+}
+
+func headquarterOf(swiftCode string) string {
+	return swiftCode[:8] + "XXX"
+}
+
+// snapshot copies both maps so that an atomic bulk call can restore them.
+// Best-effort calls never roll back and do not take one.
+func (r *Repository) snapshot() (map[string]model.SwiftCode, map[string]string) {
+	codes := make(map[string]model.SwiftCode, len(r.codes))
+	for k, v := range r.codes {
+		codes[k] = v
+	}
+	branches := make(map[string]string, len(r.branches))
+	for k, v := range r.branches {
+		branches[k] = v
+	}
+	return codes, branches
+}
+
+func (r *Repository) linkOrphans(headquarter string) {
+	prefix := headquarter[:8]
+	for swiftCode, hq := range r.branches {
+		if hq == "" && strings.HasPrefix(swiftCode, prefix) {
+			r.branches[swiftCode] = headquarter
+		}
+	}
+}
+
+func (r *Repository) branchesOf(headquarter string) []model.SwiftCode {
+	branches := []model.SwiftCode{}
+	for swiftCode, hq := range r.branches {
+		if hq == headquarter {
+			branches = append(branches, r.codes[swiftCode])
+		}
+	}
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].SwiftCode < branches[j].SwiftCode
+	})
+	return branches
+}
+
+func (r *Repository) create(code model.SwiftCode) error {
+	if _, ok := r.codes[code.SwiftCode]; ok {
+		return store.ErrConflict
+	}
+	r.codes[code.SwiftCode] = code
+
+	if len(code.SwiftCode) < 8 {
+		return nil
+	}
+	if !code.IsHeadquarter && len(code.SwiftCode) == 11 {
+		if _, ok := r.branches[code.SwiftCode]; !ok {
+			hq := headquarterOf(code.SwiftCode)
+			if _, exists := r.codes[hq]; !exists {
+				hq = ""
+			}
+			r.branches[code.SwiftCode] = hq
+		}
+		return nil
+	}
+	r.linkOrphans(code.SwiftCode)
+	return nil
+}
+
+func (r *Repository) delete(swiftCode string) error {
+	if _, ok := r.codes[swiftCode]; !ok {
+		return store.ErrNotFound
+	}
+	for _, hq := range r.branches {
+		if hq == swiftCode {
+			return store.ErrHasBranches
+		}
+	}
+	delete(r.codes, swiftCode)
+	delete(r.branches, swiftCode)
+	return nil
+}
+
+func (r *Repository) update(swiftCode string, change func(model.SwiftCode) model.SwiftCode) (model.SwiftCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.codes[swiftCode]
+	if !ok {
+		return model.SwiftCode{}, store.ErrNotFound
+	}
+	updated := change(current)
+	updated.SwiftCode = current.SwiftCode
+	updated.IsHeadquarter = current.IsHeadquarter
+	updated.Provenance = nil
+	r.codes[swiftCode] = updated
+	return updated, nil
+}
+
+func (r *Repository) Create(ctx context.Context, code model.SwiftCode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code.Provenance = nil
+	return r.create(code)
+}
+
+func (r *Repository) Update(ctx context.Context, code model.SwiftCode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := r.update(code.SwiftCode, func(model.SwiftCode) model.SwiftCode {
+		return code
+	})
+	return err
+}
+
+func (r *Repository) Patch(ctx context.Context, swiftCode string, patch model.SwiftCodePatch) (model.SwiftCode, error) {
+	if err := ctx.Err(); err != nil {
+		return model.SwiftCode{}, err
+	}
+	return r.update(swiftCode, patch.Apply)
+}
+
+func (r *Repository) Delete(ctx context.Context, swiftCode string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.delete(swiftCode)
+}
+
+func (r *Repository) BulkCreate(ctx context.Context, codes []model.SwiftCode, atomic bool) (store.BulkResult, error) {
+	if err := ctx.Err(); err != nil {
+		return store.BulkResult{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := store.BulkResult{Errors: make([]error, len(codes))}
+	var savedCodes map[string]model.SwiftCode
+	var savedBranches map[string]string
+	if atomic {
+		savedCodes, savedBranches = r.snapshot()
+	}
+	for i, code := range codes {
+		code.Provenance = nil
+		result.Errors[i] = r.create(code)
+	}
+	if atomic && result.Failed() > 0 {
+		r.codes, r.branches = savedCodes, savedBranches
+		return result, nil
+	}
+	result.Committed = true
+	return result, nil
+}
+
+func (r *Repository) BulkDelete(ctx context.Context, swiftCodes []string, atomic bool) (store.BulkResult, error) {
+	if err := ctx.Err(); err != nil {
+		return store.BulkResult{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order := store.BranchesFirst(swiftCodes)
+
+	result := store.BulkResult{Errors: make([]error, len(swiftCodes))}
+	var savedCodes map[string]model.SwiftCode
+	var savedBranches map[string]string
+	if atomic {
+		savedCodes, savedBranches = r.snapshot()
+	}
+	for _, i := range order {
+		result.Errors[i] = r.delete(swiftCodes[i])
+	}
+	if atomic && result.Failed() > 0 {
+		r.codes, r.branches = savedCodes, savedBranches
+		return result, nil
+	}
+	result.Committed = true
+	return result, nil
+}
+
+// Reset drops every code and branch, like emptying both tables.
+func (r *Repository) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes = make(map[string]model.SwiftCode)
+	r.branches = make(map[string]string)
+}
@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBranchLinking(t *testing.T) {
+	repo := New()
+
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH")))
+	assert.Equal(t, "", repo.branches["BREXPLPWWAL"])
+
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA")))
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWKAT", "MBANK", "KATOWICE")))
+	assert.ErrorIs(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWKAT", "MBANK", "KATOWICE")), store.ErrConflict)
+
+	hq, branches, err := repo.Get(t.Context(), "BREXPLPWXXX")
+	require.NoError(t, err)
+	assert.True(t, hq.IsHeadquarter)
+	assert.Equal(t, []string{"BREXPLPWKAT", "BREXPLPWWAL"}, storetest.Codes(branches))
+
+	_, branches, err = repo.Get(t.Context(), "BREXPLPWKAT")
+	require.NoError(t, err)
+	assert.Nil(t, branches)
+
+	_, _, err = repo.Get(t.Context(), "BREXPLPWGDA")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestDeleteRestrictsAndCascades(t *testing.T) {
+	repo := New()
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA")))
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH")))
+
+	assert.ErrorIs(t, repo.Delete(t.Context(), "BREXPLPWXXX"), store.ErrHasBranches)
+	require.NoError(t, repo.Delete(t.Context(), "BREXPLPWWAL"))
+	assert.NotContains(t, repo.branches, "BREXPLPWWAL")
+	require.NoError(t, repo.Delete(t.Context(), "BREXPLPWXXX"))
+	assert.ErrorIs(t, repo.Delete(t.Context(), "BREXPLPWXXX"), store.ErrNotFound)
+}
+
+func TestPatchKeepsHeadquarter(t *testing.T) {
+	repo := New()
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA")))
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH")))
+
+	demote := false
+	updated, err := repo.Patch(t.Context(), "BREXPLPWXXX", model.SwiftCodePatch{IsHeadquarter: &demote})
+	require.NoError(t, err)
+	assert.True(t, updated.IsHeadquarter)
+	assert.Equal(t, "BREXPLPWXXX", repo.branches["BREXPLPWWAL"])
+	assert.NotContains(t, repo.branches, "BREXPLPWXXX")
+
+	assert.ErrorIs(t, repo.Update(t.Context(), storetest.SwiftCode("BREXPLPWGDA", "MBANK", "GDANSK")), store.ErrNotFound)
+}
+
+func TestBulk(t *testing.T) {
+	repo := New()
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA")))
+
+	result, err := repo.BulkCreate(t.Context(), []model.SwiftCode{
+		storetest.SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH"),
+		storetest.SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+	}, true)
+	require.NoError(t, err)
+	assert.False(t, result.Committed)
+	assert.ErrorIs(t, result.Errors[1], store.ErrConflict)
+	assert.NotContains(t, repo.codes, "BREXPLPWWAL")
+
+	result, err = repo.BulkCreate(t.Context(), []model.SwiftCode{
+		storetest.SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH"),
+		storetest.SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+	}, false)
+	require.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.Equal(t, 1, result.Failed())
+	assert.Equal(t, "BREXPLPWXXX", repo.branches["BREXPLPWWAL"])
+
+	result, err = repo.BulkDelete(t.Context(), []string{"BREXPLPWXXX", "BREXPLPWWAL"}, true)
+	require.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.Empty(t, repo.codes)
+	assert.Empty(t, repo.branches)
+}
+
+func TestLoadAndDryRun(t *testing.T) {
+	repo := New()
+	loaded := repo.Load([]parser.SwiftRecord{
+		{SwiftCode: "BREXPLPWWAL", ISO2Code: "PL", BankName: "MBANK", TownName: "WALBRZYCH"},
+		{SwiftCode: "BREXPLPWKAT", ISO2Code: "PL", BankName: "MBANK", TownName: "KATOWICE"},
+		{SwiftCode: "BREXPLPWKAT", ISO2Code: "PL", BankName: "MBANK", TownName: "KATOWICE"},
+	})
+	assert.Equal(t, 2, loaded)
+
+	diff, err := repo.DryRun(t.Context(), func(add func(parser.SwiftRecord) error) error {
+		for _, record := range []parser.SwiftRecord{
+			{SwiftCode: "BREXPLPWXXX", ISO2Code: "PL", BankName: "MBANK", TownName: "WARSZAWA", IsHeadquarter: true},
+			{SwiftCode: "BREXPLPWWAL", ISO2Code: "PL", BankName: "MBANK SA", TownName: "WALBRZYCH"},
+		} {
+			if err := add(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BREXPLPWXXX"}, diff.Inserted)
+	assert.Equal(t, []string{"BREXPLPWKAT"}, diff.Deleted)
+	require.Len(t, diff.Updated, 1)
+	assert.Equal(t, []store.FieldChange{{Field: "bankName", Old: "MBANK", New: "MBANK SA"}}, diff.Updated[0].Changes)
+	require.Len(t, diff.Relinked, 1)
+	assert.Nil(t, diff.Relinked[0].OldHeadquarter)
+	assert.Equal(t, "BREXPLPWXXX", *diff.Relinked[0].NewHeadquarter)
+	assert.Len(t, repo.codes, 2)
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	repo := New()
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BANKPLPWXXX", "BANK", "WARSZAWA")))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, repo.Create(t.Context(), storetest.SwiftCode(fmt.Sprintf("BANKPLPW%03d", i), "BANK", "WARSZAWA")))
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, _, err := repo.Get(t.Context(), "BANKPLPWXXX")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	_, branches, err := repo.Get(t.Context(), "BANKPLPWXXX")
+	require.NoError(t, err)
+	assert.Len(t, branches, 20)
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.SwiftCodeRepository {
+		return New()
+	})
+}
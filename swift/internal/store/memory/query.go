@@ -0,0 +1,253 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/store"
+)
+
+// Search weights follow the setweight labels of the search_vector column:
+// bank name A, town B, address C.
+var searchWeights = []float64{1.0, 0.4, 0.2}
+
+func matchesFilter(code model.SwiftCode, f store.ListFilter) bool {
+	if f.CountryISO2 != "" && code.CountryISO2 != f.CountryISO2 {
+		return false
+	}
+	if f.IsHeadquarter != nil && code.IsHeadquarter != *f.IsHeadquarter {
+		return false
+	}
+	if f.BankNamePrefix != "" && !strings.HasPrefix(strings.ToUpper(code.BankName), strings.ToUpper(f.BankNamePrefix)) {
+		return false
+	}
+	if f.TownName != "" && !strings.EqualFold(code.TownName, f.TownName) {
+		return false
+	}
+	return true
+}
+
+func (r *Repository) filter(f store.ListFilter) []model.SwiftCode {
+	codes := []model.SwiftCode{}
+	for _, code := range r.codes {
+		if matchesFilter(code, f) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+func sortCodes(codes []model.SwiftCode, field store.SortField, desc bool) {
+	sort.Slice(codes, func(i, j int) bool {
+		return less(codes[i], codes[j], field) != desc
+	})
+}
+
+func less(a, b model.SwiftCode, field store.SortField) bool {
+	ka, kb := store.SortKey(a, field), store.SortKey(b, field)
+	if ka != kb {
+		return ka < kb
+	}
+	return a.SwiftCode < b.SwiftCode
+}
+
+func (r *Repository) Get(ctx context.Context, swiftCode string) (model.SwiftCode, []model.SwiftCode, error) {
+	if err := ctx.Err(); err != nil {
+		return model.SwiftCode{}, nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	code, ok := r.codes[swiftCode]
+	if !ok {
+		return model.SwiftCode{}, nil, store.ErrNotFound
+	}
+	if !code.IsHeadquarter {
+		return code, nil, nil
+	}
+	branches := r.branchesOf(swiftCode)
+	if len(branches) == 0 {
+		return code, nil, nil
+	}
+	return code, branches, nil
+}
+
+func (r *Repository) ListByCountry(ctx context.Context, q store.CountryQuery) (store.CountryPage, error) {
+	if err := ctx.Err(); err != nil {
+		return store.CountryPage{}, err
+	}
+	q, err := q.Normalize()
+	if err != nil {
+		return store.CountryPage{}, err
+	}
+
+	r.mu.RLock()
+	codes := r.filter(q.ListFilter)
+	r.mu.RUnlock()
+
+	total := len(codes)
+	ascending := q.Ascending()
+	sortCodes(codes, q.Sort, !ascending)
+	if q.Cursor != nil {
+		mark := model.SwiftCode{SwiftCode: q.Cursor.SwiftCode}
+		if q.Sort == store.SortBankName {
+			mark.BankName = q.Cursor.Key
+		}
+		start := sort.Search(len(codes), func(i int) bool {
+			if ascending {
+				return less(mark, codes[i], q.Sort)
+			}
+			return less(codes[i], mark, q.Sort)
+		})
+		codes = codes[start:]
+	}
+	if len(codes) > q.Limit+1 {
+		codes = codes[:q.Limit+1]
+	}
+	return store.NewCountryPage(q, codes, total), nil
+}
+
+// searchRank mimics ts_rank over prefix terms closely enough for ordering:
+// every word has to prefix-match a word of the bank name, town or address,
+// and each contributes the weight of the best field it was found in.
+func searchRank(code model.SwiftCode, words []string) (float64, bool) {
+	fields := [][]string{
+		store.SearchWords(code.BankName),
+		store.SearchWords(code.TownName),
+		store.SearchWords(code.Address),
+	}
+	rank := 0.0
+	for _, word := range words {
+		best := 0.0
+		for i, fieldWords := range fields {
+			for _, candidate := range fieldWords {
+				if strings.HasPrefix(candidate, word) && searchWeights[i] > best {
+					best = searchWeights[i]
+				}
+			}
+		}
+		if best == 0 {
+			return 0, false
+		}
+		rank += best
+	}
+	return rank / float64(len(words)), true
+}
+
+func (r *Repository) Search(ctx context.Context, q store.SearchQuery) ([]model.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	q = q.Normalize()
+	results := []model.SearchResult{}
+	words := store.SearchWords(q.Text)
+	if len(words) == 0 {
+		return results, nil
+	}
+
+	filter := store.ListFilter{CountryISO2: q.CountryISO2, TownName: q.TownName, IsHeadquarter: q.IsHeadquarter}
+	r.mu.RLock()
+	for _, code := range r.filter(filter) {
+		if rank, ok := searchRank(code, words); ok {
+			results = append(results, model.SearchResult{SwiftCode: code, Rank: rank})
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].SwiftCode.SwiftCode < results[j].SwiftCode.SwiftCode
+	})
+	if len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results, nil
+}
+
+func (r *Repository) MatchBankName(ctx context.Context, q store.MatchQuery) ([]model.MatchCandidate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	q = q.Normalize()
+
+	candidates := []model.MatchCandidate{}
+	r.mu.RLock()
+	for _, code := range r.filter(store.ListFilter{CountryISO2: q.CountryISO2}) {
+		if score := store.Similarity(q.Name, code.BankName); score >= q.Threshold {
+			candidates = append(candidates, model.MatchCandidate{SwiftCode: code, Score: score})
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.IsHeadquarter != b.IsHeadquarter {
+			return a.IsHeadquarter
+		}
+		return a.SwiftCode.SwiftCode < b.SwiftCode.SwiftCode
+	})
+	if len(candidates) > q.Limit {
+		candidates = candidates[:q.Limit]
+	}
+	return candidates, nil
+}
+
+func (r *Repository) Lookup(ctx context.Context, swiftCodes []string) ([]store.LookupEntry, []string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	find := func(swiftCode string) (model.SwiftCode, bool) {
+		code, ok := r.codes[swiftCode]
+		return code, ok
+	}
+	entries, notFound := store.LookupEntries(swiftCodes, find, r.branchesOf)
+	return entries, notFound, nil
+}
+
+// Provenance is always empty: nothing is imported through the imports table.
+func (r *Repository) Provenance(ctx context.Context, swiftCodes []string) (map[string]model.Provenance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return map[string]model.Provenance{}, nil
+}
+
+// Stream copies the matching rows first so fn can take as long as it likes
+// without blocking writers.
+func (r *Repository) Stream(ctx context.Context, q store.ExportQuery, fn func(model.SwiftCode) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if q.Sort == "" {
+		q.Sort = store.SortSwiftCode
+	}
+	if _, ok := store.ParseSortField(string(q.Sort)); !ok {
+		return fmt.Errorf("unknown sort field %q", q.Sort)
+	}
+
+	r.mu.RLock()
+	codes := r.filter(q.ListFilter)
+	r.mu.RUnlock()
+
+	sortCodes(codes, q.Sort, q.Desc)
+	for _, code := range codes {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
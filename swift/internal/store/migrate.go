@@ -0,0 +1,283 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one numbered schema change read from NNN_name.up.sql and
+// NNN_name.down.sql.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+	// Unknown marks a version recorded in the database that this binary
+	// has no files for, e.g. after a rollback to an older release.
+	Unknown bool
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads the migrations in the root of fsys, ordered by
+// version. Every version needs both an up and a down file; other files are
+// ignored.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		script, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// OutdatedSchemaError lists the migrations a database is missing.
+type OutdatedSchemaError struct {
+	Pending []Migration
+}
+
+func (e *OutdatedSchemaError) Error() string {
+	names := make([]string, len(e.Pending))
+	for i, m := range e.Pending {
+		names[i] = m.String()
+	}
+	return fmt.Sprintf("database schema is missing %d migration(s): %s", len(e.Pending), strings.Join(names, ", "))
+}
+
+// Migrator applies and reverts migrations, recording them in
+// schema_migrations. Each step runs in its own transaction together with its
+// schema_migrations row, so a failed step leaves nothing behind, and a second
+// migrator running at the same time skips steps the first one already took.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	createQuery := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		    version BIGINT PRIMARY KEY,
+		    name TEXT NOT NULL,
+		    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	_, err := m.db.ExecContext(ctx, createQuery)
+	return err
+}
+
+// Status reports every known and recorded migration. It only reads, so a
+// database without schema_migrations shows every migration as pending.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied := make(map[int64]MigrationStatus)
+	rows, err := m.db.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations ORDER BY version")
+	if isUndefinedTable(err) {
+		return m.statuses(applied), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status MigrationStatus
+		var appliedAt time.Time
+		if err := rows.Scan(&status.Version, &status.Name, &appliedAt); err != nil {
+			return nil, err
+		}
+		status.AppliedAt = &appliedAt
+		status.Unknown = true
+		applied[status.Version] = status
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return m.statuses(applied), nil
+}
+
+// statuses merges the recorded migrations in applied with the known ones.
+func (m *Migrator) statuses(applied map[int64]MigrationStatus) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if recorded, ok := applied[migration.Version]; ok {
+			status.AppliedAt = recorded.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, unknown := range applied {
+		statuses = append(statuses, unknown)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
+
+func (m *Migrator) pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns an *OutdatedSchemaError when migrations are pending. Like
+// Status, it never changes the database.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return &OutdatedSchemaError{Pending: pending}
+	}
+	return nil
+}
+
+// Up applies pending migrations up to and including target, or all of them
+// when target is 0, and returns the ones it applied.
+func (m *Migrator) Up(ctx context.Context, target int64) ([]Migration, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	pending, err := m.pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		ok, err := m.step(ctx, migration, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING", migration.Up)
+		if err != nil {
+			return applied, fmt.Errorf("%s up: %w", migration, err)
+		}
+		if ok {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// Down reverts the steps most recent migrations, newest first, and returns
+// the ones it reverted.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		status := statuses[i]
+		if status.AppliedAt == nil {
+			continue
+		}
+		if status.Unknown {
+			return reverted, fmt.Errorf("migration %s is not known to this binary and cannot be reverted", status.Migration)
+		}
+		ok, err := m.step(ctx, status.Migration, "DELETE FROM schema_migrations WHERE version = $1 AND name = $2", status.Down)
+		if err != nil {
+			return reverted, fmt.Errorf("%s down: %w", status.Migration, err)
+		}
+		if ok {
+			reverted = append(reverted, status.Migration)
+		}
+	}
+	return reverted, nil
+}
+
+// step records the migration and runs script in one transaction. Touching
+// the schema_migrations row first serializes concurrent migrators; if the
+// row was already changed by someone else, step reports false.
+func (m *Migrator) step(ctx context.Context, migration Migration, record, script string) (bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := tx.ExecContext(ctx, record, migration.Version, migration.Name)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	changed, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if changed == 0 {
+		return false, tx.Rollback()
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
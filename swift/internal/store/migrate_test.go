@@ -0,0 +1,195 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/mbartnicki80/swift/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	createSchemaMigrationsQuery = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+`
+	selectSchemaMigrationsQuery = `SELECT version, name, applied_at FROM schema_migrations ORDER BY version`
+	recordMigrationQuery        = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT (version) DO NOTHING`
+	forgetMigrationQuery        = `DELETE FROM schema_migrations WHERE version = $1 AND name = $2`
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "first", Up: "CREATE TABLE a (id INT)", Down: "DROP TABLE a"},
+	{Version: 2, Name: "second", Up: "ALTER TABLE a ADD COLUMN b TEXT", Down: "ALTER TABLE a DROP COLUMN b"},
+}
+
+func newMigrationMock(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewMigrator(db, testMigrations), mock
+}
+
+func expectApplied(mock sqlmock.Sqlmock, versions ...int64) {
+	rows := sqlmock.NewRows([]string{"version", "name", "applied_at"})
+	for _, version := range versions {
+		name := "first"
+		if version != 1 {
+			name = "second"
+		}
+		rows.AddRow(version, name, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	}
+	mock.ExpectQuery(selectSchemaMigrationsQuery).WillReturnRows(rows)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_second.up.sql":   {Data: []byte("ALTER TABLE a ADD COLUMN b TEXT")},
+		"002_second.down.sql": {Data: []byte("ALTER TABLE a DROP COLUMN b")},
+		"001_first.up.sql":    {Data: []byte("CREATE TABLE a (id INT)")},
+		"001_first.down.sql":  {Data: []byte("DROP TABLE a")},
+		"migrations.go":       {Data: []byte("package migrations")},
+	}
+	loaded, err := LoadMigrations(fsys)
+	require.NoError(t, err)
+	assert.Equal(t, testMigrations, loaded)
+
+	delete(fsys, "002_second.down.sql")
+	_, err = LoadMigrations(fsys)
+	assert.ErrorContains(t, err, "002_second needs both an up and a down file")
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := LoadMigrations(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+	for i, m := range loaded {
+		assert.Equal(t, int64(i+1), m.Version, "versions have no gaps")
+	}
+}
+
+func TestMigratorUp(t *testing.T) {
+	t.Run("applies pending migrations in order", func(t *testing.T) {
+		migrator, mock := newMigrationMock(t)
+		mock.ExpectExec(createSchemaMigrationsQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		expectApplied(mock, 1)
+		mock.ExpectBegin()
+		mock.ExpectExec(recordMigrationQuery).WithArgs(int64(2), "second").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("ALTER TABLE a ADD COLUMN b TEXT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		applied, err := migrator.Up(t.Context(), 0)
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		assert.Equal(t, "002_second", applied[0].String())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stops at the target version", func(t *testing.T) {
+		migrator, mock := newMigrationMock(t)
+		mock.ExpectExec(createSchemaMigrationsQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		expectApplied(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(recordMigrationQuery).WithArgs(int64(1), "first").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("CREATE TABLE a (id INT)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		applied, err := migrator.Up(t.Context(), 1)
+		require.NoError(t, err)
+		assert.Len(t, applied, 1)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("skips a migration another migrator recorded", func(t *testing.T) {
+		migrator, mock := newMigrationMock(t)
+		mock.ExpectExec(createSchemaMigrationsQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		expectApplied(mock, 1)
+		mock.ExpectBegin()
+		mock.ExpectExec(recordMigrationQuery).WithArgs(int64(2), "second").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		applied, err := migrator.Up(t.Context(), 0)
+		require.NoError(t, err)
+		assert.Empty(t, applied)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back a failing migration", func(t *testing.T) {
+		migrator, mock := newMigrationMock(t)
+		mock.ExpectExec(createSchemaMigrationsQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		expectApplied(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(recordMigrationQuery).WithArgs(int64(1), "first").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("CREATE TABLE a (id INT)").WillReturnError(errors.New("syntax error"))
+		mock.ExpectRollback()
+
+		applied, err := migrator.Up(t.Context(), 0)
+		require.ErrorContains(t, err, "001_first up: syntax error")
+		assert.Empty(t, applied)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigratorDown(t *testing.T) {
+	t.Run("reverts newest first", func(t *testing.T) {
+		migrator, mock := newMigrationMock(t)
+		expectApplied(mock, 1, 2)
+		mock.ExpectBegin()
+		mock.ExpectExec(forgetMigrationQuery).WithArgs(int64(2), "second").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("ALTER TABLE a DROP COLUMN b").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		reverted, err := migrator.Down(t.Context(), 1)
+		require.NoError(t, err)
+		require.Len(t, reverted, 1)
+		assert.Equal(t, int64(2), reverted[0].Version)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses to revert an unknown migration", func(t *testing.T) {
+		migrator, mock := newMigrationMock(t)
+		expectApplied(mock, 1, 2, 3)
+
+		_, err := migrator.Down(t.Context(), 1)
+		require.ErrorContains(t, err, "003_second is not known to this binary")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigratorStatusAndCheck(t *testing.T) {
+	migrator, mock := newMigrationMock(t)
+	expectApplied(mock, 1)
+
+	statuses, err := migrator.Status(t.Context())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	require.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+
+	expectApplied(mock, 1)
+	var outdated *OutdatedSchemaError
+	require.ErrorAs(t, migrator.Check(t.Context()), &outdated)
+	assert.EqualError(t, outdated, "database schema is missing 1 migration(s): 002_second")
+
+	expectApplied(mock, 1, 2)
+	require.NoError(t, migrator.Check(t.Context()))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorCheckWithoutTable(t *testing.T) {
+	migrator, mock := newMigrationMock(t)
+	mock.ExpectQuery(selectSchemaMigrationsQuery).WillReturnError(&pq.Error{Code: undefinedTable})
+
+	var outdated *OutdatedSchemaError
+	require.ErrorAs(t, migrator.Check(t.Context()), &outdated)
+	assert.Len(t, outdated.Pending, 2)
+	require.NoError(t, mock.ExpectationsWereMet(), "checking does not create schema_migrations")
+}
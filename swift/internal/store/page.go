@@ -0,0 +1,243 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mbartnicki80/swift/internal/model"
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+type SortField string
+
+const (
+	SortSwiftCode SortField = "swiftCode"
+	SortBankName  SortField = "bankName"
+)
+
+var sortColumns = map[SortField]string{
+	SortSwiftCode: "swift_code",
+	SortBankName:  "bank_name",
+}
+
+func ParseSortField(s string) (SortField, bool) {
+	field := SortField(s)
+	_, ok := sortColumns[field]
+	return field, ok
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a country listing. Key holds the value of the
+// sort column and SwiftCode breaks ties, so pages stay stable under inserts.
+type Cursor struct {
+	Sort      SortField `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	Key       string    `json:"k"`
+	SwiftCode string    `json:"c"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if _, ok := sortColumns[cursor.Sort]; !ok || cursor.SwiftCode == "" {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+type ListFilter struct {
+	CountryISO2    string
+	IsHeadquarter  *bool
+	BankNamePrefix string
+	TownName       string
+}
+
+type CountryQuery struct {
+	ListFilter
+	Limit  int
+	Sort   SortField
+	Desc   bool
+	Cursor *Cursor
+}
+
+type CountryPage struct {
+	SwiftCodes []model.SwiftCode
+	Total      int
+	Next       *Cursor
+	Prev       *Cursor
+}
+
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func (q ListFilter) conditions() ([]string, []any) {
+	var conditions []string
+	var args []any
+	if q.CountryISO2 != "" {
+		args = append(args, q.CountryISO2)
+		conditions = append(conditions, fmt.Sprintf("country_iso2_code = $%d", len(args)))
+	}
+	if q.IsHeadquarter != nil {
+		args = append(args, *q.IsHeadquarter)
+		conditions = append(conditions, fmt.Sprintf("is_headquarter = $%d", len(args)))
+	}
+	if q.BankNamePrefix != "" {
+		args = append(args, EscapeLike(q.BankNamePrefix)+"%")
+		conditions = append(conditions, fmt.Sprintf("bank_name ILIKE $%d", len(args)))
+	}
+	if q.TownName != "" {
+		args = append(args, q.TownName)
+		conditions = append(conditions, fmt.Sprintf("UPPER(town_name) = UPPER($%d)", len(args)))
+	}
+	return conditions, args
+}
+
+func SortKey(code model.SwiftCode, sort SortField) string {
+	if sort == SortBankName {
+		return code.BankName
+	}
+	return code.SwiftCode
+}
+
+// Normalize fills in defaults and rejects queries no backend can serve.
+func (q CountryQuery) Normalize() (CountryQuery, error) {
+	if q.Sort == "" {
+		q.Sort = SortSwiftCode
+	}
+	if _, ok := sortColumns[q.Sort]; !ok {
+		return q, fmt.Errorf("unknown sort field %q", q.Sort)
+	}
+	if q.Limit <= 0 || q.Limit > MaxPageSize {
+		q.Limit = DefaultPageSize
+	}
+	if q.Cursor != nil && (q.Cursor.Sort != q.Sort || q.Cursor.Desc != q.Desc) {
+		return q, ErrInvalidCursor
+	}
+	if q.CountryISO2 == "" {
+		return q, errors.New("country listing requires a country code")
+	}
+	return q, nil
+}
+
+// Backward reports whether the query walks towards the previous page.
+func (q CountryQuery) Backward() bool {
+	return q.Cursor != nil && q.Cursor.Backward
+}
+
+// Ascending reports the direction rows have to be scanned in, which is the
+// reverse of the requested order when paging backward.
+func (q CountryQuery) Ascending() bool {
+	return q.Desc == q.Backward()
+}
+
+// NewCountryPage builds a page from up to q.Limit+1 rows read in scan order
+// after the cursor; the extra row only signals that another page exists.
+func NewCountryPage(q CountryQuery, codes []model.SwiftCode, total int) CountryPage {
+	page := CountryPage{Total: total}
+
+	more := len(codes) > q.Limit
+	if more {
+		codes = codes[:q.Limit]
+	}
+	if q.Backward() {
+		for i, j := 0, len(codes)-1; i < j; i, j = i+1, j-1 {
+			codes[i], codes[j] = codes[j], codes[i]
+		}
+	}
+	page.SwiftCodes = codes
+	if len(codes) == 0 {
+		return page
+	}
+
+	hasNext, hasPrev := more, q.Cursor != nil
+	if q.Backward() {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		last := codes[len(codes)-1]
+		page.Next = &Cursor{Sort: q.Sort, Desc: q.Desc, Key: SortKey(last, q.Sort), SwiftCode: last.SwiftCode}
+	}
+	if hasPrev {
+		first := codes[0]
+		page.Prev = &Cursor{Sort: q.Sort, Desc: q.Desc, Key: SortKey(first, q.Sort), SwiftCode: first.SwiftCode, Backward: true}
+	}
+	return page
+}
+
+func FetchCountryPage(ctx context.Context, db *sql.DB, q CountryQuery) (CountryPage, error) {
+	q, err := q.Normalize()
+	if err != nil {
+		return CountryPage{}, err
+	}
+	column := sortColumns[q.Sort]
+
+	var total int
+	conditions, args := q.conditions()
+	countQuery := "SELECT COUNT(*) FROM swift_codes WHERE " + strings.Join(conditions, " AND ")
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return CountryPage{}, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if !q.Ascending() {
+		direction, comparison = "DESC", "<"
+	}
+	if q.Cursor != nil {
+		args = append(args, q.Cursor.Key, q.Cursor.SwiftCode)
+		conditions = append(conditions, fmt.Sprintf("(%s, swift_code) %s ($%d, $%d)", column, comparison, len(args)-1, len(args)))
+	}
+	args = append(args, q.Limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+		       code_type, town_name, time_zone
+		FROM swift_codes
+		WHERE %s
+		ORDER BY %s %s, swift_code %s
+		LIMIT $%d
+		`, strings.Join(conditions, " AND "), column, direction, direction, len(args))
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return CountryPage{}, err
+	}
+	defer rows.Close()
+
+	codes := []model.SwiftCode{}
+	for rows.Next() {
+		var code model.SwiftCode
+		err := rows.Scan(&code.Address, &code.BankName, &code.CountryISO2, &code.IsHeadquarter, &code.SwiftCode, &code.CountryName,
+			&code.CodeType, &code.TownName, &code.TimeZone)
+		if err != nil {
+			return CountryPage{}, err
+		}
+		codes = append(codes, code)
+	}
+	if err := rows.Err(); err != nil {
+		return CountryPage{}, err
+	}
+
+	return NewCountryPage(q, codes, total), nil
+}
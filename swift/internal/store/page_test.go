@@ -0,0 +1,119 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	countCountryQuery = `SELECT COUNT(*) FROM swift_codes WHERE country_iso2_code = $1`
+
+	firstCountryPageQuery = `
+SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+       code_type, town_name, time_zone
+FROM swift_codes
+WHERE country_iso2_code = $1
+ORDER BY swift_code ASC, swift_code ASC
+LIMIT $2
+`
+
+	countFilteredQuery = `SELECT COUNT(*) FROM swift_codes WHERE country_iso2_code = $1 AND is_headquarter = $2 AND bank_name ILIKE $3 AND UPPER(town_name) = UPPER($4)`
+
+	prevBankNamePageQuery = `
+SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+       code_type, town_name, time_zone
+FROM swift_codes
+WHERE country_iso2_code = $1 AND is_headquarter = $2 AND bank_name ILIKE $3 AND UPPER(town_name) = UPPER($4) AND (bank_name, swift_code) > ($5, $6)
+ORDER BY bank_name ASC, swift_code ASC
+LIMIT $7
+`
+)
+
+var pageColumns = []string{
+	"address", "bank_name", "country_iso2_code", "is_headquarter", "swift_code", "country_name",
+	"code_type", "town_name", "time_zone",
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{Sort: SortBankName, Desc: true, Key: "PKO BP", SwiftCode: "BPKOPLPWXXX", Backward: true}
+	decoded, err := DecodeCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, decoded)
+
+	_, err = DecodeCursor("not a cursor")
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestFetchCountryPage(t *testing.T) {
+	t.Run("first page has next cursor only", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(countCountryQuery).WithArgs("PL").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(firstCountryPageQuery).WithArgs("PL", 3).
+			WillReturnRows(sqlmock.NewRows(pageColumns).
+				AddRow("Warsaw", "PKO", "PL", true, "BPKOPLPWXXX", "POLAND", "BIC11", "WARSZAWA", "Europe/Warsaw").
+				AddRow("Krakow", "PKO", "PL", false, "BPKOPLPW002", "POLAND", "BIC11", "KRAKOW", "Europe/Warsaw").
+				AddRow("Gdansk", "PKO", "PL", false, "BPKOPLPW003", "POLAND", "BIC11", "GDANSK", "Europe/Warsaw"))
+
+		page, err := FetchCountryPage(t.Context(), db, CountryQuery{ListFilter: ListFilter{CountryISO2: "PL"}, Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 3, page.Total)
+		require.Len(t, page.SwiftCodes, 2)
+		require.NotNil(t, page.Next)
+		assert.Equal(t, "BPKOPLPW002", page.Next.SwiftCode)
+		assert.Nil(t, page.Prev)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("backward page with filters is reversed", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		isHeadquarter := false
+		cursor := Cursor{Sort: SortBankName, Desc: true, Key: "MBANK", SwiftCode: "BREXPLPW002", Backward: true}
+
+		mock.ExpectQuery(countFilteredQuery).WithArgs("PL", false, `100\%%`, "warszawa").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+		mock.ExpectQuery(prevBankNamePageQuery).WithArgs("PL", false, `100\%%`, "warszawa", "MBANK", "BREXPLPW002", 3).
+			WillReturnRows(sqlmock.NewRows(pageColumns).
+				AddRow("", "PEKAO", "PL", false, "PKOPPLPW001", "POLAND", "", "WARSZAWA", "").
+				AddRow("", "PKO", "PL", false, "BPKOPLPW001", "POLAND", "", "WARSZAWA", ""))
+
+		page, err := FetchCountryPage(t.Context(), db, CountryQuery{
+			ListFilter: ListFilter{
+				CountryISO2:    "PL",
+				IsHeadquarter:  &isHeadquarter,
+				BankNamePrefix: "100%",
+				TownName:       "warszawa",
+			},
+			Limit:  2,
+			Sort:   SortBankName,
+			Desc:   true,
+			Cursor: &cursor,
+		})
+		require.NoError(t, err)
+		require.Len(t, page.SwiftCodes, 2)
+		assert.Equal(t, "BPKOPLPW001", page.SwiftCodes[0].SwiftCode)
+		assert.Nil(t, page.Prev)
+		require.NotNil(t, page.Next)
+		assert.Equal(t, "PEKAO", page.Next.Key)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("cursor from another sort is rejected", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		cursor := Cursor{Sort: SortBankName, Key: "PKO", SwiftCode: "BPKOPLPWXXX"}
+		_, err = FetchCountryPage(t.Context(), db, CountryQuery{ListFilter: ListFilter{CountryISO2: "PL"}, Cursor: &cursor})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
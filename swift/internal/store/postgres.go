@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+)
+
+type PostgresRepository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+var (
+	_ SwiftCodeRepository = (*PostgresRepository)(nil)
+	_ ImportRepository    = (*PostgresRepository)(nil)
+)
+
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// SetQueryTimeout bounds every call except Stream and DryRun, which run for
+// as long as their caller keeps producing or consuming rows. Zero disables
+// the limit.
+func (r *PostgresRepository) SetQueryTimeout(timeout time.Duration) {
+	r.timeout = timeout
+}
+
+func (r *PostgresRepository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+func (r *PostgresRepository) Get(ctx context.Context, swiftCode string) (model.SwiftCode, []model.SwiftCode, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	code, branches, err := FetchSwiftCode(ctx, r.db, swiftCode)
+	return code, branches, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) ListByCountry(ctx context.Context, q CountryQuery) (CountryPage, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	page, err := FetchCountryPage(ctx, r.db, q)
+	return page, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) Search(ctx context.Context, q SearchQuery) ([]model.SearchResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	results, err := SearchSwiftCodes(ctx, r.db, q)
+	return results, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) MatchBankName(ctx context.Context, q MatchQuery) ([]model.MatchCandidate, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	candidates, err := MatchBankName(ctx, r.db, q)
+	return candidates, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) Lookup(ctx context.Context, swiftCodes []string) ([]LookupEntry, []string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	entries, notFound, err := LookupSwiftCodes(ctx, r.db, swiftCodes)
+	return entries, notFound, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) Provenance(ctx context.Context, swiftCodes []string) (map[string]model.Provenance, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	provenance, err := FetchProvenance(ctx, r.db, swiftCodes)
+	return provenance, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) Stream(ctx context.Context, q ExportQuery, fn func(model.SwiftCode) error) error {
+	return ContextError(ctx, StreamSwiftCodes(ctx, r.db, q, fn))
+}
+
+func (r *PostgresRepository) Create(ctx context.Context, code model.SwiftCode) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	return ContextError(ctx, InsertNewSwiftCode(ctx, r.db, code))
+}
+
+func (r *PostgresRepository) Update(ctx context.Context, code model.SwiftCode) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	return ContextError(ctx, UpdateSwiftCode(ctx, r.db, code))
+}
+
+func (r *PostgresRepository) Patch(ctx context.Context, swiftCode string, patch model.SwiftCodePatch) (model.SwiftCode, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	code, err := PatchSwiftCode(ctx, r.db, swiftCode, patch)
+	return code, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) Delete(ctx context.Context, swiftCode string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	return ContextError(ctx, DeleteSwiftCode(ctx, r.db, swiftCode))
+}
+
+func (r *PostgresRepository) BulkCreate(ctx context.Context, codes []model.SwiftCode, atomic bool) (BulkResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result, err := BulkCreate(ctx, r.db, codes, atomic)
+	return result, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) BulkDelete(ctx context.Context, swiftCodes []string, atomic bool) (BulkResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result, err := BulkDelete(ctx, r.db, swiftCodes, atomic)
+	return result, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) Imports(ctx context.Context) ([]model.Import, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	imports, err := FetchImports(ctx, r.db)
+	return imports, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) Import(ctx context.Context, id int64) (model.Import, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	imp, err := FetchImport(ctx, r.db, id)
+	return imp, ContextError(ctx, err)
+}
+
+func (r *PostgresRepository) DryRun(ctx context.Context, stream func(add func(parser.SwiftRecord) error) error) (Diff, error) {
+	diff, err := DryRun(ctx, r.db, stream)
+	return diff, ContextError(ctx, err)
+}
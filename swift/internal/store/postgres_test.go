@@ -0,0 +1,59 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostgresConformance runs the shared suite against the test database
+// from ../../.env and is skipped when none is configured.
+func TestPostgresConformance(t *testing.T) {
+	godotenv.Load("../../.env")
+	if os.Getenv("TEST_DB_HOST") == "" {
+		t.Skip("TEST_DB_HOST is not set")
+	}
+
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("TEST_DB_HOST"), os.Getenv("TEST_DB_PORT"), os.Getenv("TEST_DB_USER"),
+		os.Getenv("TEST_DB_PASSWORD"), os.Getenv("TEST_DB_NAME"))
+	db, err := sql.Open("postgres", connStr)
+	require.NoError(t, err)
+	defer db.Close()
+
+	storetest.Run(t, func(t *testing.T) store.SwiftCodeRepository {
+		_, err := db.Exec("DELETE FROM branches")
+		require.NoError(t, err)
+		_, err = db.Exec("DELETE FROM swift_codes")
+		require.NoError(t, err)
+		return store.NewPostgresRepository(db)
+	})
+}
+
+func TestPostgresRepositoryQueryTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := store.NewPostgresRepository(db)
+	repo.SetQueryTimeout(10 * time.Millisecond)
+
+	mock.ExpectExec("DELETE FROM swift_codes WHERE swift_code=$1").
+		WithArgs("BREXPLPWXXX").
+		WillDelayFor(time.Second).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.Delete(t.Context(), "BREXPLPWXXX")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, store.ErrNotFound)
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+)
+
+// SwiftCodeRepository is what the HTTP handlers need from a storage backend.
+// Implementations return ErrNotFound, ErrConflict and ErrHasBranches for the
+// matching cases so handlers can map them without knowing the backend.
+// Every method stops when ctx is done and then returns an error wrapping
+// ctx.Err().
+type SwiftCodeRepository interface {
+	Get(ctx context.Context, swiftCode string) (model.SwiftCode, []model.SwiftCode, error)
+	ListByCountry(ctx context.Context, q CountryQuery) (CountryPage, error)
+	Search(ctx context.Context, q SearchQuery) ([]model.SearchResult, error)
+	MatchBankName(ctx context.Context, q MatchQuery) ([]model.MatchCandidate, error)
+	Lookup(ctx context.Context, swiftCodes []string) ([]LookupEntry, []string, error)
+	Provenance(ctx context.Context, swiftCodes []string) (map[string]model.Provenance, error)
+	Stream(ctx context.Context, q ExportQuery, fn func(model.SwiftCode) error) error
+
+	Create(ctx context.Context, code model.SwiftCode) error
+	Update(ctx context.Context, code model.SwiftCode) error
+	Patch(ctx context.Context, swiftCode string, patch model.SwiftCodePatch) (model.SwiftCode, error)
+	Delete(ctx context.Context, swiftCode string) error
+	BulkCreate(ctx context.Context, codes []model.SwiftCode, atomic bool) (BulkResult, error)
+	BulkDelete(ctx context.Context, swiftCodes []string, atomic bool) (BulkResult, error)
+}
+
+type ImportRepository interface {
+	Imports(ctx context.Context) ([]model.Import, error)
+	Import(ctx context.Context, id int64) (model.Import, error)
+	DryRun(ctx context.Context, stream func(add func(parser.SwiftRecord) error) error) (Diff, error)
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mbartnicki80/swift/internal/model"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+type SearchQuery struct {
+	Text          string
+	CountryISO2   string
+	TownName      string
+	IsHeadquarter *bool
+	Limit         int
+}
+
+func SearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchTerms turns free text into a prefix tsquery, so "deut bank hamb"
+// matches "DEUTSCHE BANK" in "HAMBURG". It returns "" when nothing searchable
+// is left.
+func SearchTerms(text string) string {
+	words := SearchWords(text)
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = word + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+func (q SearchQuery) Normalize() SearchQuery {
+	if q.Limit <= 0 || q.Limit > MaxSearchLimit {
+		q.Limit = DefaultSearchLimit
+	}
+	return q
+}
+
+func SearchSwiftCodes(ctx context.Context, db *sql.DB, q SearchQuery) ([]model.SearchResult, error) {
+	q = q.Normalize()
+
+	conditions := []string{"search_vector @@ query"}
+	args := []any{SearchTerms(q.Text)}
+	if q.CountryISO2 != "" {
+		args = append(args, q.CountryISO2)
+		conditions = append(conditions, fmt.Sprintf("country_iso2_code = $%d", len(args)))
+	}
+	if q.TownName != "" {
+		args = append(args, q.TownName)
+		conditions = append(conditions, fmt.Sprintf("UPPER(town_name) = UPPER($%d)", len(args)))
+	}
+	if q.IsHeadquarter != nil {
+		args = append(args, *q.IsHeadquarter)
+		conditions = append(conditions, fmt.Sprintf("is_headquarter = $%d", len(args)))
+	}
+	args = append(args, q.Limit)
+
+	query := fmt.Sprintf(`
+		SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+		       code_type, town_name, time_zone, ts_rank(search_vector, query) AS rank
+		FROM swift_codes, to_tsquery('simple', $1) AS query
+		WHERE %s
+		ORDER BY rank DESC, swift_code
+		LIMIT $%d
+		`, strings.Join(conditions, " AND "), len(args))
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []model.SearchResult{}
+	for rows.Next() {
+		var result model.SearchResult
+		err := rows.Scan(&result.Address, &result.BankName, &result.CountryISO2, &result.IsHeadquarter, &result.SwiftCode.SwiftCode, &result.CountryName,
+			&result.CodeType, &result.TownName, &result.TimeZone, &result.Rank)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const searchQuery = `
+SELECT address, bank_name, country_iso2_code, is_headquarter, swift_code, country_name,
+       code_type, town_name, time_zone, ts_rank(search_vector, query) AS rank
+FROM swift_codes, to_tsquery('simple', $1) AS query
+WHERE search_vector @@ query AND UPPER(town_name) = UPPER($2)
+ORDER BY rank DESC, swift_code
+LIMIT $3
+`
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, "deutsche:* & bank:* & hamburg:*", SearchTerms("Deutsche Bank, Hamburg"))
+	assert.Equal(t, "société:* & générale:*", SearchTerms("Société  Générale"))
+	assert.Equal(t, "", SearchTerms(" &|!:* "))
+}
+
+func TestSearchSwiftCodes(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(searchQuery).
+		WithArgs("deutsche:*", "hamburg", DefaultSearchLimit).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"address", "bank_name", "country_iso2_code", "is_headquarter", "swift_code", "country_name",
+			"code_type", "town_name", "time_zone", "rank",
+		}).AddRow("ALSTERTOR 1", "DEUTSCHE BANK AG", "DE", true, "DEUTDEHHXXX", "GERMANY", "BIC11", "HAMBURG", "Europe/Berlin", 0.6).
+			AddRow("ADOLPHSPLATZ 7", "DEUTSCHE BANK AG", "DE", false, "DEUTDEHH222", "GERMANY", "BIC11", "HAMBURG", "Europe/Berlin", 0.4))
+
+	results, err := SearchSwiftCodes(t.Context(), db, SearchQuery{Text: "deutsche", TownName: "hamburg"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "DEUTDEHHXXX", results[0].SwiftCode.SwiftCode)
+	assert.Equal(t, 0.4, results[1].Rank)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
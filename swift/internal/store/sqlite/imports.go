@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/mbartnicki80/swift/internal/store"
+)
+
+// Imports is always empty: the SQLite schema has no imports table.
+func (r *Repository) Imports(ctx context.Context) ([]model.Import, error) {
+	return []model.Import{}, nil
+}
+
+func (r *Repository) Import(ctx context.Context, id int64) (model.Import, error) {
+	return model.Import{}, store.ErrNotFound
+}
+
+// diffColumns holds the columns behind store.DiffFields, in the same order.
+var diffColumns = []string{"country_iso2_code", "bank_name", "address", "country_name", "code_type", "town_name", "time_zone"}
+
+func queryCodes(ctx context.Context, tx *sql.Tx, query string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	codes := []string{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+// DryRun loads the records into a temporary table inside a transaction that
+// is always rolled back, and diffs it against swift_codes with the same
+// rules as store.DryRun. The transaction is opened read-only, which the
+// driver begins as DEFERRED instead of IMMEDIATE: it only writes to the temp
+// schema, so it must not hold the main database's write lock while the
+// upload is decoded.
+func (r *Repository) DryRun(ctx context.Context, stream func(add func(parser.SwiftRecord) error) error) (store.Diff, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return store.Diff{}, err
+	}
+	defer tx.Rollback()
+
+	createIncomingQuery := `
+		CREATE TEMP TABLE incoming_swift_codes (
+		    country_iso2_code TEXT NOT NULL,
+		    swift_code TEXT PRIMARY KEY,
+		    bank_name TEXT NOT NULL,
+		    address TEXT,
+		    country_name TEXT NOT NULL,
+		    is_headquarter BOOLEAN NOT NULL,
+		    code_type TEXT NOT NULL,
+		    town_name TEXT NOT NULL,
+		    time_zone TEXT NOT NULL
+		)
+	`
+	if _, err := tx.ExecContext(ctx, createIncomingQuery); err != nil {
+		return store.Diff{}, err
+	}
+
+	insertIncomingQuery := `
+		INSERT INTO incoming_swift_codes (country_iso2_code, swift_code,
+		                                  bank_name, address,
+		                                  country_name, is_headquarter,
+		                                  code_type, town_name, time_zone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (swift_code) DO NOTHING
+	`
+	err = stream(func(record parser.SwiftRecord) error {
+		_, err := tx.ExecContext(ctx, insertIncomingQuery, record.ISO2Code, record.SwiftCode, record.BankName,
+			record.Address, record.Country, record.IsHeadquarter,
+			record.CodeType, record.TownName, record.TimeZone)
+		return err
+	})
+	if err != nil {
+		return store.Diff{}, err
+	}
+
+	var diff store.Diff
+	diff.Inserted, err = queryCodes(ctx, tx, `
+		SELECT swift_code FROM incoming_swift_codes
+		WHERE swift_code NOT IN (SELECT swift_code FROM swift_codes)
+		ORDER BY swift_code`)
+	if err != nil {
+		return store.Diff{}, err
+	}
+	diff.Deleted, err = queryCodes(ctx, tx, `
+		SELECT swift_code FROM swift_codes
+		WHERE swift_code NOT IN (SELECT swift_code FROM incoming_swift_codes)
+		ORDER BY swift_code`)
+	if err != nil {
+		return store.Diff{}, err
+	}
+	if diff.Updated, err = updatedCodes(ctx, tx); err != nil {
+		return store.Diff{}, err
+	}
+	if diff.Relinked, err = relinkedBranches(ctx, tx); err != nil {
+		return store.Diff{}, err
+	}
+	return diff, nil
+}
+
+func updatedCodes(ctx context.Context, tx *sql.Tx) ([]store.CodeChange, error) {
+	columns := append(append([]string{}, diffColumns...), "is_headquarter")
+	selected := make([]string, 0, 2*len(diffColumns))
+	distinct := make([]string, len(columns))
+	for i, column := range columns {
+		distinct[i] = "s." + column + " IS NOT i." + column
+	}
+	for _, column := range diffColumns {
+		selected = append(selected, "coalesce(s."+column+", '')")
+	}
+	for _, column := range diffColumns {
+		selected = append(selected, "coalesce(i."+column+", '')")
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT s.swift_code, `+strings.Join(selected, ", ")+`
+		FROM swift_codes s
+		JOIN incoming_swift_codes i ON i.swift_code = s.swift_code
+		WHERE `+strings.Join(distinct, " OR ")+`
+		ORDER BY s.swift_code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updated := []store.CodeChange{}
+	for rows.Next() {
+		var code string
+		values := make([]string, 2*len(diffColumns))
+		dest := []any{&code}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		updated = append(updated, store.NewCodeChange(code, values[:len(diffColumns)], values[len(diffColumns):]))
+	}
+	return updated, rows.Err()
+}
+
+func relinkedBranches(ctx context.Context, tx *sql.Tx) ([]store.Relink, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT branches.swift_code, branches.headquarter, hq.swift_code
+		FROM branches
+		JOIN incoming_swift_codes i ON i.swift_code = branches.swift_code
+		LEFT JOIN incoming_swift_codes hq
+		       ON hq.is_headquarter AND hq.swift_code = substr(branches.swift_code, 1, 8) || 'XXX'
+		WHERE branches.headquarter IS NOT hq.swift_code
+		ORDER BY branches.swift_code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	relinked := []store.Relink{}
+	for rows.Next() {
+		var relink store.Relink
+		if err := rows.Scan(&relink.SwiftCode, &relink.OldHeadquarter, &relink.NewHeadquarter); err != nil {
+			return nil, err
+		}
+		relinked = append(relinked, relink)
+	}
+	return relinked, rows.Err()
+}
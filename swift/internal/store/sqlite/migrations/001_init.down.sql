@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS branches;
+DROP TABLE IF EXISTS swift_codes;
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS swift_codes (
+    country_iso2_code CHAR(2) NOT NULL,
+    swift_code VARCHAR(11) PRIMARY KEY,
+    bank_name TEXT NOT NULL,
+    address TEXT,
+    country_name TEXT NOT NULL,
+    is_headquarter BOOLEAN NOT NULL,
+    code_type VARCHAR(5) NOT NULL DEFAULT '',
+    town_name TEXT NOT NULL DEFAULT '',
+    time_zone TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS branches (
+    swift_code VARCHAR(11) PRIMARY KEY,
+    headquarter VARCHAR(11),
+    FOREIGN KEY (swift_code) REFERENCES swift_codes(swift_code) ON DELETE CASCADE,
+    FOREIGN KEY (headquarter) REFERENCES swift_codes(swift_code) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_country_swift_code ON swift_codes(country_iso2_code, swift_code);
+CREATE INDEX IF NOT EXISTS idx_country_bank_name ON swift_codes(country_iso2_code, bank_name, swift_code);
+CREATE INDEX IF NOT EXISTS idx_branches_headquarter ON branches(headquarter);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS swift_codes_search_update;
+DROP TRIGGER IF EXISTS swift_codes_search_delete;
+DROP TRIGGER IF EXISTS swift_codes_search_insert;
+DROP TABLE IF EXISTS swift_codes_search;
@@ -0,0 +1,22 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS swift_codes_search USING fts5(
+    bank_name, town_name, address,
+    content = 'swift_codes', content_rowid = 'rowid',
+    tokenize = 'unicode61 remove_diacritics 0'
+);
+
+CREATE TRIGGER IF NOT EXISTS swift_codes_search_insert AFTER INSERT ON swift_codes BEGIN
+    INSERT INTO swift_codes_search (rowid, bank_name, town_name, address)
+    VALUES (new.rowid, new.bank_name, new.town_name, new.address);
+END;
+
+CREATE TRIGGER IF NOT EXISTS swift_codes_search_delete AFTER DELETE ON swift_codes BEGIN
+    INSERT INTO swift_codes_search (swift_codes_search, rowid, bank_name, town_name, address)
+    VALUES ('delete', old.rowid, old.bank_name, old.town_name, old.address);
+END;
+
+CREATE TRIGGER IF NOT EXISTS swift_codes_search_update AFTER UPDATE ON swift_codes BEGIN
+    INSERT INTO swift_codes_search (swift_codes_search, rowid, bank_name, town_name, address)
+    VALUES ('delete', old.rowid, old.bank_name, old.town_name, old.address);
+    INSERT INTO swift_codes_search (rowid, bank_name, town_name, address)
+    VALUES (new.rowid, new.bank_name, new.town_name, new.address);
+END;
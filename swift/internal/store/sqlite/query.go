@@ -0,0 +1,317 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/store"
+)
+
+const selectCodeQuery = `
+	SELECT swift_code, address, country_name, is_headquarter, country_iso2_code, bank_name,
+	       code_type, town_name, time_zone
+	FROM swift_codes`
+
+var sortColumns = map[store.SortField]string{
+	store.SortSwiftCode: "swift_code",
+	store.SortBankName:  "bank_name",
+}
+
+func scanCode(row interface{ Scan(...any) error }, extra ...any) (model.SwiftCode, error) {
+	var code model.SwiftCode
+	var address sql.NullString
+	dest := append([]any{&code.SwiftCode, &address, &code.CountryName, &code.IsHeadquarter, &code.CountryISO2, &code.BankName,
+		&code.CodeType, &code.TownName, &code.TimeZone}, extra...)
+	err := row.Scan(dest...)
+	code.Address = address.String
+	return code, err
+}
+
+func collect(rows *sql.Rows) ([]model.SwiftCode, error) {
+	defer rows.Close()
+	codes := []model.SwiftCode{}
+	for rows.Next() {
+		code, err := scanCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+// filterConditions is store.ListFilter.conditions in SQLite: LIKE is already
+// case-insensitive for ASCII, so it replaces ILIKE.
+func filterConditions(f store.ListFilter, table string, args []any) ([]string, []any) {
+	var conditions []string
+	if f.CountryISO2 != "" {
+		args = append(args, f.CountryISO2)
+		conditions = append(conditions, fmt.Sprintf("%scountry_iso2_code = $%d", table, len(args)))
+	}
+	if f.IsHeadquarter != nil {
+		args = append(args, *f.IsHeadquarter)
+		conditions = append(conditions, fmt.Sprintf("%sis_headquarter = $%d", table, len(args)))
+	}
+	if f.BankNamePrefix != "" {
+		args = append(args, store.EscapeLike(f.BankNamePrefix)+"%")
+		conditions = append(conditions, fmt.Sprintf(`%sbank_name LIKE $%d ESCAPE '\'`, table, len(args)))
+	}
+	if f.TownName != "" {
+		args = append(args, f.TownName)
+		conditions = append(conditions, fmt.Sprintf("UPPER(%stown_name) = UPPER($%d)", table, len(args)))
+	}
+	return conditions, args
+}
+
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+func (r *Repository) Get(ctx context.Context, swiftCode string) (model.SwiftCode, []model.SwiftCode, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	code, err := scanCode(r.db.QueryRowContext(ctx, selectCodeQuery+" WHERE swift_code = $1", swiftCode))
+	if err != nil {
+		return model.SwiftCode{}, nil, notFound(err)
+	}
+	if !code.IsHeadquarter {
+		return code, nil, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, selectCodeQuery+`
+		WHERE swift_code IN (SELECT swift_code FROM branches WHERE headquarter = $1)
+		ORDER BY swift_code`, swiftCode)
+	if err != nil {
+		return code, nil, err
+	}
+	branches, err := collect(rows)
+	if err != nil || len(branches) == 0 {
+		return code, nil, err
+	}
+	return code, branches, nil
+}
+
+func (r *Repository) ListByCountry(ctx context.Context, q store.CountryQuery) (store.CountryPage, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	q, err := q.Normalize()
+	if err != nil {
+		return store.CountryPage{}, err
+	}
+	column := sortColumns[q.Sort]
+
+	var total int
+	conditions, args := filterConditions(q.ListFilter, "", nil)
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM swift_codes "+where(conditions), args...).Scan(&total); err != nil {
+		return store.CountryPage{}, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if !q.Ascending() {
+		direction, comparison = "DESC", "<"
+	}
+	if q.Cursor != nil {
+		args = append(args, q.Cursor.Key, q.Cursor.SwiftCode)
+		conditions = append(conditions, fmt.Sprintf("(%s, swift_code) %s ($%d, $%d)", column, comparison, len(args)-1, len(args)))
+	}
+	args = append(args, q.Limit+1)
+
+	query := fmt.Sprintf("%s %s ORDER BY %s %s, swift_code %s LIMIT $%d",
+		selectCodeQuery, where(conditions), column, direction, direction, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return store.CountryPage{}, err
+	}
+	codes, err := collect(rows)
+	if err != nil {
+		return store.CountryPage{}, err
+	}
+	return store.NewCountryPage(q, codes, total), nil
+}
+
+// matchExpression turns free text into an FTS5 prefix query; every word is
+// quoted so nothing in it is read as an operator.
+func matchExpression(text string) string {
+	words := store.SearchWords(text)
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + word + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// Search ranks with bm25, weighting bank name, town and address like the
+// A, B and C labels of the Postgres search_vector.
+func (r *Repository) Search(ctx context.Context, q store.SearchQuery) ([]model.SearchResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	q = q.Normalize()
+	results := []model.SearchResult{}
+	expression := matchExpression(q.Text)
+	if expression == "" {
+		return results, nil
+	}
+
+	filter := store.ListFilter{CountryISO2: q.CountryISO2, TownName: q.TownName, IsHeadquarter: q.IsHeadquarter}
+	conditions, args := filterConditions(filter, "s.", []any{expression})
+	conditions = append([]string{"swift_codes_search MATCH $1"}, conditions...)
+	args = append(args, q.Limit)
+
+	query := fmt.Sprintf(`
+		SELECT s.swift_code, s.address, s.country_name, s.is_headquarter, s.country_iso2_code, s.bank_name,
+		       s.code_type, s.town_name, s.time_zone, -bm25(swift_codes_search, 1.0, 0.4, 0.2) AS rank
+		FROM swift_codes_search
+		JOIN swift_codes s ON s.rowid = swift_codes_search.rowid
+		%s
+		ORDER BY rank DESC, s.swift_code
+		LIMIT $%d
+		`, where(conditions), len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result model.SearchResult
+		result.SwiftCode, err = scanCode(rows, &result.Rank)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// MatchBankName scores with the similarity() function registered in init,
+// which follows pg_trgm. Without a trigram index every row is scored.
+func (r *Repository) MatchBankName(ctx context.Context, q store.MatchQuery) ([]model.MatchCandidate, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	q = q.Normalize()
+
+	matchQuery := `
+		SELECT * FROM (
+			SELECT swift_code, address, country_name, is_headquarter, country_iso2_code, bank_name,
+			       code_type, town_name, time_zone, similarity(bank_name, $1) AS score
+			FROM swift_codes
+			WHERE $2 = '' OR country_iso2_code = $2
+		)
+		WHERE score >= $3
+		ORDER BY score DESC, is_headquarter DESC, swift_code
+		LIMIT $4
+	`
+	rows, err := r.db.QueryContext(ctx, matchQuery, q.Name, q.CountryISO2, q.Threshold, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := []model.MatchCandidate{}
+	for rows.Next() {
+		var candidate model.MatchCandidate
+		candidate.SwiftCode, err = scanCode(rows, &candidate.Score)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, rows.Err()
+}
+
+// Lookup passes the codes as one JSON array parameter, the SQLite stand-in
+// for = ANY($1), and otherwise follows store.LookupSwiftCodes, including its
+// UNION ALL split.
+func (r *Repository) Lookup(ctx context.Context, swiftCodes []string) ([]store.LookupEntry, []string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	codes, err := json.Marshal(swiftCodes)
+	if err != nil {
+		return nil, nil, err
+	}
+	lookupQuery := `
+		SELECT s.swift_code, s.address, s.country_name, s.is_headquarter, s.country_iso2_code, s.bank_name,
+		       s.code_type, s.town_name, s.time_zone, b.headquarter
+		FROM swift_codes s
+		LEFT JOIN branches b ON b.swift_code = s.swift_code
+		WHERE s.swift_code IN (SELECT value FROM json_each($1))
+		UNION ALL
+		SELECT s.swift_code, s.address, s.country_name, s.is_headquarter, s.country_iso2_code, s.bank_name,
+		       s.code_type, s.town_name, s.time_zone, b.headquarter
+		FROM branches b
+		JOIN swift_codes s ON s.swift_code = b.swift_code
+		WHERE b.headquarter IN (SELECT value FROM json_each($1))
+		  AND s.swift_code NOT IN (SELECT value FROM json_each($1))
+		ORDER BY 1
+	`
+	rows, err := r.db.QueryContext(ctx, lookupQuery, string(codes))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	result := store.NewLookupResult(swiftCodes)
+	for rows.Next() {
+		var headquarter sql.NullString
+		code, err := scanCode(rows, &headquarter)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.Add(code, headquarter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	entries, notFound := result.Entries()
+	return entries, notFound, nil
+}
+
+// Provenance is always empty: the SQLite schema has no imports table.
+func (r *Repository) Provenance(ctx context.Context, swiftCodes []string) (map[string]model.Provenance, error) {
+	return map[string]model.Provenance{}, nil
+}
+
+// Stream reads the rows with an ordinary query; SQLite steps through the
+// result lazily, so like the Postgres cursor it does not load everything.
+func (r *Repository) Stream(ctx context.Context, q store.ExportQuery, fn func(model.SwiftCode) error) error {
+	if q.Sort == "" {
+		q.Sort = store.SortSwiftCode
+	}
+	column, ok := sortColumns[q.Sort]
+	if !ok {
+		return fmt.Errorf("unknown sort field %q", q.Sort)
+	}
+	direction := "ASC"
+	if q.Desc {
+		direction = "DESC"
+	}
+
+	conditions, args := filterConditions(q.ListFilter, "", nil)
+	query := fmt.Sprintf("%s %s ORDER BY %s %s, swift_code %s", selectCodeQuery, where(conditions), column, direction, direction)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		code, err := scanCode(rows)
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(code); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,106 @@
+// Package sqlite stores SWIFT codes in a single SQLite file, for nodes that
+// have no Postgres. The schema mirrors migrations/ with FTS5 standing in for
+// the tsvector column and a Go similarity() function for pg_trgm.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"errors"
+	"io/fs"
+	"net/url"
+	"time"
+
+	"github.com/mbartnicki80/swift/internal/store"
+	msqlite "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+type Repository struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+var (
+	_ store.SwiftCodeRepository = (*Repository)(nil)
+	_ store.ImportRepository    = (*Repository)(nil)
+)
+
+func init() {
+	msqlite.MustRegisterDeterministicScalarFunction("similarity", 2, func(ctx *msqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		a, _ := args[0].(string)
+		b, _ := args[1].(string)
+		return store.Similarity(a, b), nil
+	})
+}
+
+// Open opens (creating if needed) the database file at path. Foreign keys
+// are enforced on every connection and transactions take the write lock up
+// front, so concurrent writers wait for busy_timeout instead of failing.
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Set("_txlock", "immediate")
+	return sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// SetQueryTimeout bounds every call except Stream, DryRun and Load, whose
+// duration depends on the number of rows. Zero disables the limit.
+func (r *Repository) SetQueryTimeout(timeout time.Duration) {
+	r.timeout = timeout
+}
+
+func (r *Repository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+// Migrations returns the embedded SQLite migrations.
+func Migrations() ([]store.Migration, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return store.LoadMigrations(sub)
+}
+
+// Migrate applies the pending embedded migrations, like
+// `swift migrate -store sqlite up`.
+func Migrate(ctx context.Context, db *sql.DB) ([]store.Migration, error) {
+	loaded, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	return store.NewMigrator(db, loaded).Up(ctx, 0)
+}
+
+// isForeignKeyViolation also matches SQLITE_CONSTRAINT_TRIGGER, which is how
+// SQLite reports an ON DELETE RESTRICT violation.
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr *msqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY || code == sqlite3.SQLITE_CONSTRAINT_TRIGGER
+}
+
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	return err
+}
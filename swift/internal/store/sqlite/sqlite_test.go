@@ -0,0 +1,191 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/mbartnicki80/swift/internal/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepository(t *testing.T) *Repository {
+	db, err := Open(filepath.Join(t.TempDir(), "swift.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = Migrate(t.Context(), db)
+	require.NoError(t, err)
+	return New(db)
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.SwiftCodeRepository {
+		return newRepository(t)
+	})
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	repo := newRepository(t)
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA")))
+
+	applied, err := Migrate(t.Context(), repo.db)
+	require.NoError(t, err)
+	assert.Empty(t, applied, "nothing is pending after the first run")
+
+	loaded, err := Migrations()
+	require.NoError(t, err)
+	migrator := store.NewMigrator(repo.db, loaded)
+	require.NoError(t, migrator.Check(t.Context()))
+
+	reverted, err := migrator.Down(t.Context(), len(loaded))
+	require.NoError(t, err)
+	require.Len(t, reverted, 2)
+	assert.Equal(t, "002_search", reverted[0].String())
+
+	var outdated *store.OutdatedSchemaError
+	require.ErrorAs(t, migrator.Check(t.Context()), &outdated)
+	assert.Len(t, outdated.Pending, 2)
+
+	applied, err = Migrate(t.Context(), repo.db)
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+	_, _, err = repo.Get(t.Context(), "BREXPLPWXXX")
+	assert.ErrorIs(t, err, store.ErrNotFound, "the down migrations dropped the data")
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA")))
+}
+
+func TestCheckLeavesFreshDatabaseAlone(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "swift.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	loaded, err := Migrations()
+	require.NoError(t, err)
+	var outdated *store.OutdatedSchemaError
+	require.ErrorAs(t, store.NewMigrator(db, loaded).Check(t.Context()), &outdated)
+	assert.Len(t, outdated.Pending, len(loaded))
+
+	var tables int
+	require.NoError(t, db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'").Scan(&tables))
+	assert.Zero(t, tables)
+}
+
+func TestSearchIndexFollowsUpdates(t *testing.T) {
+	repo := newRepository(t)
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA")))
+
+	bankName := "COMMERZBANK"
+	_, err := repo.Patch(t.Context(), "BREXPLPWXXX", model.SwiftCodePatch{BankName: &bankName})
+	require.NoError(t, err)
+
+	results, err := repo.Search(t.Context(), store.SearchQuery{Text: "mbank"})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	results, err = repo.Search(t.Context(), store.SearchQuery{Text: "commerz"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	require.NoError(t, repo.Delete(t.Context(), "BREXPLPWXXX"))
+	results, err = repo.Search(t.Context(), store.SearchQuery{Text: "commerz"})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestLoadAndDryRun(t *testing.T) {
+	repo := newRepository(t)
+	loaded, err := repo.Load(t.Context(), []parser.SwiftRecord{
+		{SwiftCode: "BREXPLPWWAL", ISO2Code: "PL", BankName: "MBANK", TownName: "WALBRZYCH"},
+		{SwiftCode: "BREXPLPWKAT", ISO2Code: "PL", BankName: "MBANK", TownName: "KATOWICE"},
+		{SwiftCode: "BREXPLPWKAT", ISO2Code: "PL", BankName: "MBANK", TownName: "KATOWICE"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, loaded)
+
+	diff, err := repo.DryRun(t.Context(), func(add func(parser.SwiftRecord) error) error {
+		for _, record := range []parser.SwiftRecord{
+			{SwiftCode: "BREXPLPWXXX", ISO2Code: "PL", BankName: "MBANK", TownName: "WARSZAWA", IsHeadquarter: true},
+			{SwiftCode: "BREXPLPWWAL", ISO2Code: "PL", BankName: "MBANK SA", TownName: "WALBRZYCH"},
+		} {
+			if err := add(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BREXPLPWXXX"}, diff.Inserted)
+	assert.Equal(t, []string{"BREXPLPWKAT"}, diff.Deleted)
+	require.Len(t, diff.Updated, 1)
+	assert.Equal(t, []store.FieldChange{{Field: "bankName", Old: "MBANK", New: "MBANK SA"}}, diff.Updated[0].Changes)
+	require.Len(t, diff.Relinked, 1)
+	assert.Nil(t, diff.Relinked[0].OldHeadquarter)
+	assert.Equal(t, "BREXPLPWXXX", *diff.Relinked[0].NewHeadquarter)
+
+	_, _, err = repo.Get(t.Context(), "BREXPLPWXXX")
+	assert.ErrorIs(t, err, store.ErrNotFound, "a dry run changes nothing")
+}
+
+func TestDryRunLetsWritersThrough(t *testing.T) {
+	repo := newRepository(t)
+
+	diff, err := repo.DryRun(t.Context(), func(add func(parser.SwiftRecord) error) error {
+		if err := add(parser.SwiftRecord{SwiftCode: "BREXPLPWXXX", ISO2Code: "PL", BankName: "MBANK", IsHeadquarter: true}); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+		defer cancel()
+		return repo.Create(ctx, storetest.SwiftCode("PKOPPLPWXXX", "PKO", "WARSZAWA"))
+	})
+	require.NoError(t, err, "a write during a dry run does not wait for its lock")
+	assert.Equal(t, []string{"BREXPLPWXXX"}, diff.Inserted)
+	assert.Equal(t, []string{"PKOPPLPWXXX"}, diff.Deleted)
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	repo := newRepository(t)
+	require.NoError(t, repo.Create(t.Context(), storetest.SwiftCode("BANKPLPWXXX", "BANK", "WARSZAWA")))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, repo.Create(t.Context(), storetest.SwiftCode(fmt.Sprintf("BANKPLPW%03d", i), "BANK", "WARSZAWA")))
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, _, err := repo.Get(t.Context(), "BANKPLPWXXX")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	_, branches, err := repo.Get(t.Context(), "BANKPLPWXXX")
+	require.NoError(t, err)
+	assert.Len(t, branches, 20)
+}
+
+func TestQueryTimeout(t *testing.T) {
+	repo := newRepository(t)
+	repo.SetQueryTimeout(time.Millisecond)
+
+	slowUpload := func(add func(parser.SwiftRecord) error) error {
+		time.Sleep(20 * time.Millisecond)
+		return add(parser.SwiftRecord{SwiftCode: "BREXPLPWXXX", ISO2Code: "PL", BankName: "MBANK", IsHeadquarter: true})
+	}
+	diff, err := repo.DryRun(t.Context(), slowUpload)
+	require.NoError(t, err, "a dry run is bounded by its caller, not the query timeout")
+	assert.Equal(t, []string{"BREXPLPWXXX"}, diff.Inserted)
+
+	repo.SetQueryTimeout(time.Nanosecond)
+	_, _, err = repo.Get(t.Context(), "BREXPLPWXXX")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
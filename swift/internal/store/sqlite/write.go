@@ -0,0 +1,236 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/mbartnicki80/swift/internal/store"
+)
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertCode adds one code with the same branch rules as the Postgres store:
+// a branch points at its prefix+XXX headquarter if that exists and stays
+// unlinked otherwise, and a new headquarter picks up unlinked branches.
+func insertCode(ctx context.Context, ex execer, code model.SwiftCode) error {
+	insertSwiftCodeQuery := `
+		INSERT INTO swift_codes (country_iso2_code, swift_code,
+		                         bank_name, address, country_name,
+		                         is_headquarter, code_type,
+		                         town_name, time_zone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (swift_code) DO NOTHING
+	`
+	res, err := ex.ExecContext(ctx, insertSwiftCodeQuery, code.CountryISO2, code.SwiftCode, code.BankName,
+		code.Address, code.CountryName, code.IsHeadquarter,
+		code.CodeType, code.TownName, code.TimeZone)
+	if err != nil {
+		return err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return store.ErrConflict
+	}
+	if len(code.SwiftCode) < 8 {
+		return nil
+	}
+
+	if !code.IsHeadquarter && len(code.SwiftCode) == 11 {
+		insertBranchQuery := `
+			INSERT INTO branches (swift_code, headquarter)
+			VALUES ($1, (SELECT swift_code FROM swift_codes WHERE swift_code = $2))
+			ON CONFLICT (swift_code) DO NOTHING
+		`
+		_, err = ex.ExecContext(ctx, insertBranchQuery, code.SwiftCode, code.SwiftCode[:8]+"XXX")
+		return err
+	}
+	return linkOrphans(ctx, ex, code.SwiftCode)
+}
+
+func linkOrphans(ctx context.Context, ex execer, headquarter string) error {
+	linkBranchesQuery := `
+		UPDATE branches
+		SET headquarter = $1
+		WHERE headquarter IS NULL AND substr(swift_code, 1, 8) = $2
+	`
+	_, err := ex.ExecContext(ctx, linkBranchesQuery, headquarter, headquarter[:8])
+	return err
+}
+
+func deleteCode(ctx context.Context, ex execer, swiftCode string) error {
+	res, err := ex.ExecContext(ctx, "DELETE FROM swift_codes WHERE swift_code = $1", swiftCode)
+	if isForeignKeyViolation(err) {
+		return store.ErrHasBranches
+	}
+	if err != nil {
+		return err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) update(ctx context.Context, swiftCode string, change func(model.SwiftCode) model.SwiftCode) (model.SwiftCode, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return model.SwiftCode{}, err
+	}
+
+	current, err := scanCode(tx.QueryRowContext(ctx, selectCodeQuery+" WHERE swift_code = $1", swiftCode))
+	if err != nil {
+		tx.Rollback()
+		return model.SwiftCode{}, notFound(err)
+	}
+	updated := change(current)
+	updated.SwiftCode = current.SwiftCode
+	updated.IsHeadquarter = current.IsHeadquarter
+
+	updateSwiftCodeQuery := `
+		UPDATE swift_codes
+		SET country_iso2_code = $2, bank_name = $3, address = $4, country_name = $5,
+		    is_headquarter = $6, code_type = $7, town_name = $8, time_zone = $9
+		WHERE swift_code = $1
+	`
+	_, err = tx.ExecContext(ctx, updateSwiftCodeQuery, updated.SwiftCode, updated.CountryISO2, updated.BankName,
+		updated.Address, updated.CountryName, updated.IsHeadquarter,
+		updated.CodeType, updated.TownName, updated.TimeZone)
+	if err != nil {
+		tx.Rollback()
+		return model.SwiftCode{}, err
+	}
+
+	return updated, tx.Commit()
+}
+
+func (r *Repository) Create(ctx context.Context, code model.SwiftCode) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := insertCode(ctx, tx, code); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) Update(ctx context.Context, code model.SwiftCode) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	_, err := r.update(ctx, code.SwiftCode, func(model.SwiftCode) model.SwiftCode {
+		return code
+	})
+	return err
+}
+
+func (r *Repository) Patch(ctx context.Context, swiftCode string, patch model.SwiftCodePatch) (model.SwiftCode, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	return r.update(ctx, swiftCode, patch.Apply)
+}
+
+func (r *Repository) Delete(ctx context.Context, swiftCode string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	return deleteCode(ctx, r.db, swiftCode)
+}
+
+func finishBulk(tx *sql.Tx, result store.BulkResult, atomic bool) (store.BulkResult, error) {
+	if atomic && result.Failed() > 0 {
+		return result, tx.Rollback()
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	result.Committed = true
+	return result, nil
+}
+
+func (r *Repository) BulkCreate(ctx context.Context, codes []model.SwiftCode, atomic bool) (store.BulkResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := store.BulkResult{Errors: make([]error, len(codes))}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	for i, code := range codes {
+		err := insertCode(ctx, tx, code)
+		if errors.Is(err, store.ErrConflict) {
+			result.Errors[i] = err
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return result, err
+		}
+	}
+	return finishBulk(tx, result, atomic)
+}
+
+// BulkDelete deletes branches before headquarters, like the Postgres store.
+// SQLite only undoes the failing statement on a constraint error, so no
+// savepoints are needed to carry on after one.
+func (r *Repository) BulkDelete(ctx context.Context, swiftCodes []string, atomic bool) (store.BulkResult, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	result := store.BulkResult{Errors: make([]error, len(swiftCodes))}
+
+	order := store.BranchesFirst(swiftCodes)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	for _, i := range order {
+		err := deleteCode(ctx, tx, swiftCodes[i])
+		if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrHasBranches) {
+			result.Errors[i] = err
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return result, err
+		}
+	}
+	return finishBulk(tx, result, atomic)
+}
+
+// Load inserts validated records in one transaction the way an insert-mode
+// import does, skipping codes that already exist. It returns how many rows
+// were inserted.
+func (r *Repository) Load(ctx context.Context, records []parser.SwiftRecord) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	inserted := 0
+	for _, record := range records {
+		err := insertCode(ctx, tx, store.ModelFromRecord(record))
+		if errors.Is(err, store.ErrConflict) {
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		inserted++
+	}
+	return inserted, tx.Commit()
+}
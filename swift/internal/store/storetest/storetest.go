@@ -0,0 +1,369 @@
+// Package storetest holds the behaviour every store.SwiftCodeRepository has
+// to share, so the Postgres, SQLite and in-memory backends run one suite.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the suite; newRepository must return an empty repository.
+func Run(t *testing.T, newRepository func(t *testing.T) store.SwiftCodeRepository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo store.SwiftCodeRepository)
+	}{
+		{"BranchLinking", testBranchLinking},
+		{"DeleteRestrictsAndCascades", testDeleteRestrictsAndCascades},
+		{"PatchKeepsHeadquarter", testPatchKeepsHeadquarter},
+		{"ListByCountry", testListByCountry},
+		{"Search", testSearch},
+		{"MatchBankName", testMatchBankName},
+		{"Lookup", testLookup},
+		{"BulkCreate", testBulkCreate},
+		{"BulkDelete", testBulkDelete},
+		{"Stream", testStream},
+		{"CancelledContext", testCancelledContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepository(t))
+		})
+	}
+}
+
+func SwiftCode(code, bankName, town string) model.SwiftCode {
+	return model.SwiftCode{
+		SwiftCode:     code,
+		BankName:      bankName,
+		TownName:      town,
+		Address:       "UL. " + town + " 1",
+		CountryISO2:   code[4:6],
+		CountryName:   "POLAND",
+		CodeType:      "BIC11",
+		TimeZone:      "Europe/Warsaw",
+		IsHeadquarter: code[8:] == "XXX",
+	}
+}
+
+func Codes(codes []model.SwiftCode) []string {
+	out := make([]string, len(codes))
+	for i, code := range codes {
+		out[i] = code.SwiftCode
+	}
+	return out
+}
+
+func create(t *testing.T, repo store.SwiftCodeRepository, codes ...model.SwiftCode) {
+	t.Helper()
+	for _, code := range codes {
+		require.NoError(t, repo.Create(t.Context(), code))
+	}
+}
+
+func branches(t *testing.T, repo store.SwiftCodeRepository, swiftCode string) []string {
+	t.Helper()
+	_, branches, err := repo.Get(t.Context(), swiftCode)
+	require.NoError(t, err)
+	return Codes(branches)
+}
+
+func testBranchLinking(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo,
+		SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH"),
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+		SwiftCode("BREXPLPWKAT", "MBANK", "KATOWICE"),
+	)
+	assert.ErrorIs(t, repo.Create(t.Context(), SwiftCode("BREXPLPWKAT", "MBANK", "KATOWICE")), store.ErrConflict)
+
+	hq, _, err := repo.Get(t.Context(), "BREXPLPWXXX")
+	require.NoError(t, err)
+	assert.Equal(t, SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"), hq)
+	assert.ElementsMatch(t, []string{"BREXPLPWKAT", "BREXPLPWWAL"}, branches(t, repo, "BREXPLPWXXX"))
+	assert.Empty(t, branches(t, repo, "BREXPLPWKAT"))
+
+	_, _, err = repo.Get(t.Context(), "BREXPLPWGDA")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func testDeleteRestrictsAndCascades(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo,
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+		SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH"),
+	)
+
+	assert.ErrorIs(t, repo.Delete(t.Context(), "BREXPLPWXXX"), store.ErrHasBranches)
+	require.NoError(t, repo.Delete(t.Context(), "BREXPLPWWAL"))
+	require.NoError(t, repo.Delete(t.Context(), "BREXPLPWXXX"))
+	assert.ErrorIs(t, repo.Delete(t.Context(), "BREXPLPWXXX"), store.ErrNotFound)
+
+	// The branch row went with its code, so a new branch starts unlinked
+	// and is picked up by the next headquarter.
+	create(t, repo, SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH"), SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"))
+	assert.Equal(t, []string{"BREXPLPWWAL"}, branches(t, repo, "BREXPLPWXXX"))
+}
+
+func testPatchKeepsHeadquarter(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo,
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+		SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH"),
+	)
+
+	town := "KRAKOW"
+	updated, err := repo.Patch(t.Context(), "BREXPLPWWAL", model.SwiftCodePatch{TownName: &town})
+	require.NoError(t, err)
+	assert.Equal(t, "KRAKOW", updated.TownName)
+	assert.Equal(t, "MBANK", updated.BankName)
+
+	demote := false
+	updated, err = repo.Patch(t.Context(), "BREXPLPWXXX", model.SwiftCodePatch{IsHeadquarter: &demote})
+	require.NoError(t, err)
+	assert.True(t, updated.IsHeadquarter, "isHeadquarter follows the XXX suffix")
+	hq := SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA")
+	hq.IsHeadquarter = false
+	require.NoError(t, repo.Update(t.Context(), hq))
+	assert.Equal(t, []string{"BREXPLPWWAL"}, branches(t, repo, "BREXPLPWXXX"))
+
+	_, err = repo.Patch(t.Context(), "BREXPLPWGDA", model.SwiftCodePatch{TownName: &town})
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.ErrorIs(t, repo.Update(t.Context(), SwiftCode("BREXPLPWGDA", "MBANK", "GDANSK")), store.ErrNotFound)
+}
+
+func testListByCountry(t *testing.T, repo store.SwiftCodeRepository) {
+	for i := 0; i < 5; i++ {
+		create(t, repo, SwiftCode(fmt.Sprintf("BANKPLP%dXXX", i), fmt.Sprintf("BANK %d", 4-i), "WARSZAWA"))
+	}
+	create(t, repo, SwiftCode("BANKPLP0KRK", "ALIOR", "Krakow"), SwiftCode("BANKDEFFXXX", "BANK", "BERLIN"))
+	pl := store.ListFilter{CountryISO2: "PL"}
+
+	first, err := repo.ListByCountry(t.Context(), store.CountryQuery{ListFilter: pl, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 6, first.Total)
+	assert.Equal(t, []string{"BANKPLP0KRK", "BANKPLP0XXX"}, Codes(first.SwiftCodes))
+	assert.Nil(t, first.Prev)
+	require.NotNil(t, first.Next)
+
+	second, err := repo.ListByCountry(t.Context(), store.CountryQuery{ListFilter: pl, Limit: 2, Cursor: first.Next})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BANKPLP1XXX", "BANKPLP2XXX"}, Codes(second.SwiftCodes))
+	require.NotNil(t, second.Prev)
+
+	back, err := repo.ListByCountry(t.Context(), store.CountryQuery{ListFilter: pl, Limit: 2, Cursor: second.Prev})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BANKPLP0KRK", "BANKPLP0XXX"}, Codes(back.SwiftCodes))
+	assert.Nil(t, back.Prev)
+
+	byName, err := repo.ListByCountry(t.Context(), store.CountryQuery{ListFilter: pl, Limit: 3, Sort: store.SortBankName, Desc: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BANKPLP0XXX", "BANKPLP1XXX", "BANKPLP2XXX"}, Codes(byName.SwiftCodes))
+	byName, err = repo.ListByCountry(t.Context(), store.CountryQuery{ListFilter: pl, Limit: 3, Sort: store.SortBankName, Desc: true, Cursor: byName.Next})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BANKPLP3XXX", "BANKPLP4XXX", "BANKPLP0KRK"}, Codes(byName.SwiftCodes))
+	assert.Nil(t, byName.Next)
+
+	notHeadquarter := false
+	filtered, err := repo.ListByCountry(t.Context(), store.CountryQuery{ListFilter: store.ListFilter{
+		CountryISO2: "PL", IsHeadquarter: &notHeadquarter, BankNamePrefix: "al", TownName: "KRAKOW",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, filtered.Total)
+	assert.Equal(t, []string{"BANKPLP0KRK"}, Codes(filtered.SwiftCodes))
+
+	percent, err := repo.ListByCountry(t.Context(), store.CountryQuery{ListFilter: store.ListFilter{CountryISO2: "PL", BankNamePrefix: "%"}})
+	require.NoError(t, err)
+	assert.Zero(t, percent.Total)
+}
+
+func testSearch(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo,
+		SwiftCode("DEUTPLPXXXX", "DEUTSCHE BANK POLSKA", "WARSZAWA"),
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+		SwiftCode("PKOPPLPWXXX", "PKO BANK POLSKI", "DEUTSCHLAND"),
+	)
+
+	results, err := repo.Search(t.Context(), store.SearchQuery{Text: "deut"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "DEUTPLPXXXX", results[0].SwiftCode.SwiftCode)
+	assert.Greater(t, results[0].Rank, results[1].Rank)
+
+	results, err = repo.Search(t.Context(), store.SearchQuery{Text: "bank warsz"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DEUTPLPXXXX"}, searchCodes(results))
+
+	results, err = repo.Search(t.Context(), store.SearchQuery{Text: "bank", TownName: "deutschland"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PKOPPLPWXXX"}, searchCodes(results))
+
+	results, err = repo.Search(t.Context(), store.SearchQuery{Text: "ban", Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func searchCodes(results []model.SearchResult) []string {
+	out := make([]string, len(results))
+	for i, result := range results {
+		out[i] = result.SwiftCode.SwiftCode
+	}
+	return out
+}
+
+func testMatchBankName(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo,
+		SwiftCode("DEUTPLPXXXX", "DEUTSCHE BANK POLSKA", "WARSZAWA"),
+		SwiftCode("DEUTPLPX002", "DEUTSCHE BANK POLSKA", "KRAKOW"),
+		SwiftCode("DEUTDEFFXXX", "DEUTSCHE BANK AG", "FRANKFURT"),
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+	)
+
+	candidates, err := repo.MatchBankName(t.Context(), store.MatchQuery{Name: "DEUTSHE BANK POLSKA", CountryISO2: "PL"})
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	assert.Equal(t, "DEUTPLPXXXX", candidates[0].SwiftCode.SwiftCode, "headquarters come first on equal scores")
+	assert.Equal(t, "DEUTPLPX002", candidates[1].SwiftCode.SwiftCode)
+	assert.InDelta(t, candidates[0].Score, candidates[1].Score, 0.0001)
+
+	candidates, err = repo.MatchBankName(t.Context(), store.MatchQuery{Name: "DEUTSCHE BANK AG", Threshold: 0.9})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DEUTDEFFXXX"}, matchCodes(candidates))
+}
+
+func matchCodes(candidates []model.MatchCandidate) []string {
+	out := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		out[i] = candidate.SwiftCode.SwiftCode
+	}
+	return out
+}
+
+func testLookup(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo,
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+		SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH"),
+		SwiftCode("PKOPPLPWXXX", "PKO BP", "WARSZAWA"),
+	)
+
+	entries, notFound, err := repo.Lookup(t.Context(), []string{"PKOPPLPWXXX", "NOPEPLPWXXX", "BREXPLPWXXX", "PKOPPLPWXXX", "BREXPLPWWAL"})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "PKOPPLPWXXX", entries[0].Code.SwiftCode)
+	assert.Equal(t, []model.SwiftCode{}, entries[0].Branches)
+	assert.Equal(t, []string{"BREXPLPWWAL"}, Codes(entries[1].Branches))
+	assert.Nil(t, entries[2].Branches)
+	assert.Equal(t, []string{"NOPEPLPWXXX"}, notFound)
+}
+
+func testBulkCreate(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo, SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"))
+	batch := []model.SwiftCode{
+		SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH"),
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+	}
+
+	result, err := repo.BulkCreate(t.Context(), batch, true)
+	require.NoError(t, err)
+	assert.False(t, result.Committed)
+	assert.NoError(t, result.Errors[0])
+	assert.ErrorIs(t, result.Errors[1], store.ErrConflict)
+	_, _, err = repo.Get(t.Context(), "BREXPLPWWAL")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	result, err = repo.BulkCreate(t.Context(), batch, false)
+	require.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.Equal(t, 1, result.Failed())
+	assert.Equal(t, []string{"BREXPLPWWAL"}, branches(t, repo, "BREXPLPWXXX"))
+}
+
+func testBulkDelete(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo,
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+		SwiftCode("BREXPLPWWAL", "MBANK", "WALBRZYCH"),
+		SwiftCode("PKOPPLPWXXX", "PKO BP", "WARSZAWA"),
+		SwiftCode("PKOPPLPW002", "PKO BP", "KRAKOW"),
+	)
+
+	result, err := repo.BulkDelete(t.Context(), []string{"BREXPLPWXXX", "NOPEPLPWXXX", "BREXPLPWWAL"}, true)
+	require.NoError(t, err)
+	assert.False(t, result.Committed)
+	assert.ErrorIs(t, result.Errors[1], store.ErrNotFound)
+	_, _, err = repo.Get(t.Context(), "BREXPLPWWAL")
+	assert.NoError(t, err)
+
+	result, err = repo.BulkDelete(t.Context(), []string{"BREXPLPWXXX", "PKOPPLPWXXX", "BREXPLPWWAL"}, false)
+	require.NoError(t, err)
+	assert.True(t, result.Committed)
+	assert.NoError(t, result.Errors[0], "branches are deleted before their headquarter")
+	assert.ErrorIs(t, result.Errors[1], store.ErrHasBranches)
+	_, _, err = repo.Get(t.Context(), "BREXPLPWXXX")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.Equal(t, []string{"PKOPPLPW002"}, branches(t, repo, "PKOPPLPWXXX"))
+}
+
+func testStream(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo,
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+		SwiftCode("ALBPPLPWXXX", "ALIOR", "WARSZAWA"),
+		SwiftCode("DEUTDEFFXXX", "DEUTSCHE BANK", "FRANKFURT"),
+	)
+
+	var streamed []string
+	err := repo.Stream(t.Context(), store.ExportQuery{Sort: store.SortBankName, Desc: true}, func(code model.SwiftCode) error {
+		streamed = append(streamed, code.SwiftCode)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BREXPLPWXXX", "DEUTDEFFXXX", "ALBPPLPWXXX"}, streamed)
+
+	streamed = nil
+	err = repo.Stream(t.Context(), store.ExportQuery{ListFilter: store.ListFilter{CountryISO2: "PL"}}, func(code model.SwiftCode) error {
+		streamed = append(streamed, code.SwiftCode)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ALBPPLPWXXX", "BREXPLPWXXX"}, streamed)
+
+	stop := fmt.Errorf("stop")
+	assert.ErrorIs(t, repo.Stream(t.Context(), store.ExportQuery{}, func(model.SwiftCode) error { return stop }), stop)
+	assert.Error(t, repo.Stream(t.Context(), store.ExportQuery{Sort: "town"}, func(model.SwiftCode) error { return nil }))
+}
+
+func testCancelledContext(t *testing.T, repo store.SwiftCodeRepository) {
+	create(t, repo,
+		SwiftCode("BREXPLPWXXX", "MBANK", "WARSZAWA"),
+		SwiftCode("ALBPPLPWXXX", "ALIOR", "WARSZAWA"),
+	)
+
+	cancelled, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, _, err := repo.Get(cancelled, "BREXPLPWXXX")
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = repo.Search(cancelled, store.SearchQuery{Text: "mbank"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, repo.Create(cancelled, SwiftCode("PKOPPLPWXXX", "PKO BP", "WARSZAWA")), context.Canceled)
+	_, err = repo.BulkDelete(cancelled, []string{"BREXPLPWXXX"}, true)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, _, err = repo.Get(t.Context(), "PKOPPLPWXXX")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	_, _, err = repo.Get(t.Context(), "BREXPLPWXXX")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	streamed := 0
+	err = repo.Stream(ctx, store.ExportQuery{}, func(model.SwiftCode) error {
+		streamed++
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, streamed, "rows after the cancellation are not streamed")
+}
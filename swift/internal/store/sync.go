@@ -0,0 +1,414 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/mbartnicki80/swift/internal/parser"
+)
+
+type SyncSummary struct {
+	Inserted []string `json:"inserted"`
+	Updated  []string `json:"updated"`
+	Deleted  []string `json:"deleted"`
+}
+
+type Syncer struct {
+	tx       *sql.Tx
+	received int
+	importID *int64
+}
+
+func NewSyncer(ctx context.Context, db *sql.DB) (*Syncer, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the primary key is needed, for ON CONFLICT: INCLUDING ALL would
+	// also copy the trigram indexes and rebuild them on every row.
+	createIncomingQuery := `
+		CREATE TEMP TABLE incoming_swift_codes (LIKE swift_codes INCLUDING DEFAULTS, PRIMARY KEY (swift_code))
+		ON COMMIT DROP
+	`
+	_, err = tx.ExecContext(ctx, createIncomingQuery)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &Syncer{tx: tx}, nil
+}
+
+func (s *Syncer) SetImportID(id int64) {
+	s.importID = &id
+}
+
+func (s *Syncer) Add(ctx context.Context, record parser.SwiftRecord) error {
+	insertIncomingQuery := `
+		INSERT INTO incoming_swift_codes (country_iso2_code, swift_code,
+		                                  bank_name, address,
+		                                  country_name, is_headquarter,
+		                                  code_type, town_name, time_zone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (swift_code) DO NOTHING
+	`
+	_, err := s.tx.ExecContext(ctx, insertIncomingQuery, record.ISO2Code, record.SwiftCode, record.BankName,
+		record.Address, record.Country, record.IsHeadquarter,
+		record.CodeType, record.TownName, record.TimeZone)
+	if err != nil {
+		s.tx.Rollback()
+		return err
+	}
+	s.received++
+	return nil
+}
+
+func (s *Syncer) Received() int {
+	return s.received
+}
+
+func (s *Syncer) Rollback() error {
+	return s.tx.Rollback()
+}
+
+func collectCodes(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+	codes := []string{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+func (s *Syncer) queryCodes(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := s.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return collectCodes(rows)
+}
+
+func (s *Syncer) Apply(ctx context.Context) (SyncSummary, error) {
+	detachBranchesQuery := `
+		UPDATE branches
+		SET headquarter = NULL
+		WHERE headquarter IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM incoming_swift_codes WHERE incoming_swift_codes.swift_code = branches.headquarter)
+	`
+	deleteQuery := `
+		DELETE FROM swift_codes
+		WHERE NOT EXISTS (SELECT 1 FROM incoming_swift_codes WHERE incoming_swift_codes.swift_code = swift_codes.swift_code)
+		RETURNING swift_code
+	`
+	updateQuery := `
+		UPDATE swift_codes
+		SET country_iso2_code = i.country_iso2_code, bank_name = i.bank_name,
+		    address = i.address, country_name = i.country_name,
+		    is_headquarter = i.is_headquarter, code_type = i.code_type,
+		    town_name = i.town_name, time_zone = i.time_zone,
+		    import_id = $1
+		FROM incoming_swift_codes i
+		WHERE swift_codes.swift_code = i.swift_code
+		  AND (swift_codes.country_iso2_code, swift_codes.bank_name, swift_codes.address,
+		       swift_codes.country_name, swift_codes.is_headquarter, swift_codes.code_type,
+		       swift_codes.town_name, swift_codes.time_zone)
+		      IS DISTINCT FROM
+		      (i.country_iso2_code, i.bank_name, i.address, i.country_name,
+		       i.is_headquarter, i.code_type, i.town_name, i.time_zone)
+		RETURNING swift_codes.swift_code
+	`
+	insertQuery := `
+		INSERT INTO swift_codes (country_iso2_code, swift_code,
+		                         bank_name, address,
+		                         country_name, is_headquarter,
+		                         code_type, town_name, time_zone,
+		                         import_id)
+		SELECT country_iso2_code, swift_code, bank_name, address,
+		       country_name, is_headquarter, code_type, town_name, time_zone,
+		       $1::BIGINT
+		FROM incoming_swift_codes
+		WHERE NOT EXISTS (SELECT 1 FROM swift_codes WHERE swift_codes.swift_code = incoming_swift_codes.swift_code)
+		RETURNING swift_code
+	`
+	insertBranchesQuery := `
+		INSERT INTO branches (swift_code, headquarter)
+		SELECT swift_code, NULL
+		FROM swift_codes
+		WHERE NOT is_headquarter
+		  AND NOT EXISTS (SELECT 1 FROM branches WHERE branches.swift_code = swift_codes.swift_code)
+	`
+
+	var summary SyncSummary
+	var err error
+
+	_, err = s.tx.ExecContext(ctx, detachBranchesQuery)
+	if err != nil {
+		s.tx.Rollback()
+		return SyncSummary{}, err
+	}
+
+	summary.Deleted, err = s.queryCodes(ctx, deleteQuery)
+	if err != nil {
+		s.tx.Rollback()
+		return SyncSummary{}, err
+	}
+
+	summary.Updated, err = s.queryCodes(ctx, updateQuery, s.importID)
+	if err != nil {
+		s.tx.Rollback()
+		return SyncSummary{}, err
+	}
+
+	summary.Inserted, err = s.queryCodes(ctx, insertQuery, s.importID)
+	if err != nil {
+		s.tx.Rollback()
+		return SyncSummary{}, err
+	}
+
+	_, err = s.tx.ExecContext(ctx, insertBranchesQuery)
+	if err != nil {
+		s.tx.Rollback()
+		return SyncSummary{}, err
+	}
+
+	err = linkOrphanBranches(ctx, s.tx)
+	if err != nil {
+		s.tx.Rollback()
+		return SyncSummary{}, err
+	}
+
+	return summary, s.tx.Commit()
+}
+
+func SyncRecords(ctx context.Context, db *sql.DB, records []parser.SwiftRecord) (SyncSummary, error) {
+	syncer, err := NewSyncer(ctx, db)
+	if err != nil {
+		return SyncSummary{}, err
+	}
+	for _, record := range records {
+		if err := syncer.Add(ctx, record); err != nil {
+			return SyncSummary{}, err
+		}
+	}
+	return syncer.Apply(ctx)
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type CodeChange struct {
+	SwiftCode string        `json:"swiftCode"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// DiffFields names the fields a dry run compares, in the order FieldValues
+// returns them. is_headquarter is compared too but never listed: it follows
+// from the code.
+var DiffFields = []string{"countryISO2", "bankName", "address", "countryName", "codeType", "townName", "timeZone"}
+
+func FieldValues(code model.SwiftCode) []string {
+	return []string{code.CountryISO2, code.BankName, code.Address, code.CountryName, code.CodeType, code.TownName, code.TimeZone}
+}
+
+// NewCodeChange lists the DiffFields whose old and new values differ.
+func NewCodeChange(swiftCode string, old, updated []string) CodeChange {
+	change := CodeChange{SwiftCode: swiftCode, Changes: []FieldChange{}}
+	for i, field := range DiffFields {
+		if old[i] != updated[i] {
+			change.Changes = append(change.Changes, FieldChange{Field: field, Old: old[i], New: updated[i]})
+		}
+	}
+	return change
+}
+
+// ModelFromRecord converts a validated import record into the stored model.
+func ModelFromRecord(record parser.SwiftRecord) model.SwiftCode {
+	return model.SwiftCode{
+		Address:       record.Address,
+		BankName:      record.BankName,
+		CodeType:      record.CodeType,
+		CountryISO2:   record.ISO2Code,
+		CountryName:   record.Country,
+		IsHeadquarter: record.IsHeadquarter,
+		SwiftCode:     record.SwiftCode,
+		TimeZone:      record.TimeZone,
+		TownName:      record.TownName,
+	}
+}
+
+type Relink struct {
+	SwiftCode      string  `json:"swiftCode"`
+	OldHeadquarter *string `json:"oldHeadquarter"`
+	NewHeadquarter *string `json:"newHeadquarter"`
+}
+
+type Diff struct {
+	Inserted []string     `json:"inserted"`
+	Updated  []CodeChange `json:"updated"`
+	Deleted  []string     `json:"deleted"`
+	Relinked []Relink     `json:"relinked"`
+}
+
+func (d Diff) Empty() bool {
+	return len(d.Inserted) == 0 && len(d.Updated) == 0 && len(d.Deleted) == 0 && len(d.Relinked) == 0
+}
+
+func headquarterName(hq *string) string {
+	if hq == nil {
+		return "(none)"
+	}
+	return *hq
+}
+
+func (d Diff) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d inserted, %d updated, %d deleted, %d relinked\n",
+		len(d.Inserted), len(d.Updated), len(d.Deleted), len(d.Relinked))
+	for _, code := range d.Inserted {
+		fmt.Fprintf(&b, "+ %s\n", code)
+	}
+	for _, change := range d.Updated {
+		fmt.Fprintf(&b, "~ %s\n", change.SwiftCode)
+		for _, field := range change.Changes {
+			fmt.Fprintf(&b, "    %s: %q -> %q\n", field.Field, field.Old, field.New)
+		}
+	}
+	for _, code := range d.Deleted {
+		fmt.Fprintf(&b, "- %s\n", code)
+	}
+	for _, relink := range d.Relinked {
+		fmt.Fprintf(&b, "> %s: %s -> %s\n", relink.SwiftCode,
+			headquarterName(relink.OldHeadquarter), headquarterName(relink.NewHeadquarter))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (s *Syncer) Diff(ctx context.Context) (Diff, error) {
+	insertedQuery := `
+		SELECT swift_code
+		FROM incoming_swift_codes
+		WHERE NOT EXISTS (SELECT 1 FROM swift_codes WHERE swift_codes.swift_code = incoming_swift_codes.swift_code)
+		ORDER BY swift_code
+	`
+	deletedQuery := `
+		SELECT swift_code
+		FROM swift_codes
+		WHERE NOT EXISTS (SELECT 1 FROM incoming_swift_codes WHERE incoming_swift_codes.swift_code = swift_codes.swift_code)
+		ORDER BY swift_code
+	`
+	updatedQuery := `
+		SELECT s.swift_code,
+		       s.country_iso2_code, s.bank_name, s.address, s.country_name, s.code_type, s.town_name, s.time_zone,
+		       i.country_iso2_code, i.bank_name, i.address, i.country_name, i.code_type, i.town_name, i.time_zone
+		FROM swift_codes s
+		JOIN incoming_swift_codes i ON i.swift_code = s.swift_code
+		WHERE (s.country_iso2_code, s.bank_name, s.address, s.country_name,
+		       s.is_headquarter, s.code_type, s.town_name, s.time_zone)
+		      IS DISTINCT FROM
+		      (i.country_iso2_code, i.bank_name, i.address, i.country_name,
+		       i.is_headquarter, i.code_type, i.town_name, i.time_zone)
+		ORDER BY s.swift_code
+	`
+	relinkedQuery := `
+		SELECT branches.swift_code, branches.headquarter, hq.swift_code
+		FROM branches
+		JOIN incoming_swift_codes i ON i.swift_code = branches.swift_code
+		LEFT JOIN incoming_swift_codes hq
+		       ON hq.is_headquarter AND hq.swift_code = LEFT(branches.swift_code, 8) || 'XXX'
+		WHERE branches.headquarter IS DISTINCT FROM hq.swift_code
+		ORDER BY branches.swift_code
+	`
+
+	var diff Diff
+	var err error
+
+	diff.Inserted, err = s.queryCodes(ctx, insertedQuery)
+	if err != nil {
+		return Diff{}, err
+	}
+	diff.Deleted, err = s.queryCodes(ctx, deletedQuery)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	rows, err := s.tx.QueryContext(ctx, updatedQuery)
+	if err != nil {
+		return Diff{}, err
+	}
+	defer rows.Close()
+
+	diff.Updated = []CodeChange{}
+	for rows.Next() {
+		var code string
+		var address, newAddress sql.NullString
+		old := make([]string, len(DiffFields))
+		updated := make([]string, len(DiffFields))
+		err := rows.Scan(&code,
+			&old[0], &old[1], &address, &old[3], &old[4], &old[5], &old[6],
+			&updated[0], &updated[1], &newAddress, &updated[3], &updated[4], &updated[5], &updated[6])
+		if err != nil {
+			return Diff{}, err
+		}
+		old[2], updated[2] = address.String, newAddress.String
+		diff.Updated = append(diff.Updated, NewCodeChange(code, old, updated))
+	}
+	if err := rows.Err(); err != nil {
+		return Diff{}, err
+	}
+
+	relinks, err := s.tx.QueryContext(ctx, relinkedQuery)
+	if err != nil {
+		return Diff{}, err
+	}
+	defer relinks.Close()
+
+	diff.Relinked = []Relink{}
+	for relinks.Next() {
+		var relink Relink
+		if err := relinks.Scan(&relink.SwiftCode, &relink.OldHeadquarter, &relink.NewHeadquarter); err != nil {
+			return Diff{}, err
+		}
+		diff.Relinked = append(diff.Relinked, relink)
+	}
+	if err := relinks.Err(); err != nil {
+		return Diff{}, err
+	}
+
+	return diff, nil
+}
+
+func DryRun(ctx context.Context, db *sql.DB, stream func(add func(parser.SwiftRecord) error) error) (Diff, error) {
+	syncer, err := NewSyncer(ctx, db)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	add := func(record parser.SwiftRecord) error {
+		return syncer.Add(ctx, record)
+	}
+	if err := stream(add); err != nil {
+		syncer.Rollback()
+		return Diff{}, err
+	}
+
+	diff, err := syncer.Diff(ctx)
+	if err != nil {
+		syncer.Rollback()
+		return Diff{}, err
+	}
+
+	return diff, syncer.Rollback()
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mbartnicki80/swift/internal/parser"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var (
+	createIncomingQuery = `
+CREATE TEMP TABLE incoming_swift_codes (LIKE swift_codes INCLUDING DEFAULTS, PRIMARY KEY (swift_code))
+ON COMMIT DROP
+`
+
+	insertIncomingQuery = `
+INSERT INTO incoming_swift_codes (country_iso2_code, swift_code,
+                                  bank_name, address,
+                                  country_name, is_headquarter,
+                                  code_type, town_name, time_zone)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (swift_code) DO NOTHING
+`
+
+	detachBranchesQuery = `
+UPDATE branches
+SET headquarter = NULL
+WHERE headquarter IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM incoming_swift_codes WHERE incoming_swift_codes.swift_code = branches.headquarter)
+`
+
+	syncDeleteQuery = `
+DELETE FROM swift_codes
+WHERE NOT EXISTS (SELECT 1 FROM incoming_swift_codes WHERE incoming_swift_codes.swift_code = swift_codes.swift_code)
+RETURNING swift_code
+`
+
+	syncUpdateQuery = `
+UPDATE swift_codes
+SET country_iso2_code = i.country_iso2_code, bank_name = i.bank_name,
+    address = i.address, country_name = i.country_name,
+    is_headquarter = i.is_headquarter, code_type = i.code_type,
+    town_name = i.town_name, time_zone = i.time_zone,
+    import_id = $1
+FROM incoming_swift_codes i
+WHERE swift_codes.swift_code = i.swift_code
+  AND (swift_codes.country_iso2_code, swift_codes.bank_name, swift_codes.address,
+       swift_codes.country_name, swift_codes.is_headquarter, swift_codes.code_type,
+       swift_codes.town_name, swift_codes.time_zone)
+      IS DISTINCT FROM
+      (i.country_iso2_code, i.bank_name, i.address, i.country_name,
+       i.is_headquarter, i.code_type, i.town_name, i.time_zone)
+RETURNING swift_codes.swift_code
+`
+
+	syncInsertQuery = `
+INSERT INTO swift_codes (country_iso2_code, swift_code,
+                         bank_name, address,
+                         country_name, is_headquarter,
+                         code_type, town_name, time_zone,
+                         import_id)
+SELECT country_iso2_code, swift_code, bank_name, address,
+       country_name, is_headquarter, code_type, town_name, time_zone,
+       $1::BIGINT
+FROM incoming_swift_codes
+WHERE NOT EXISTS (SELECT 1 FROM swift_codes WHERE swift_codes.swift_code = incoming_swift_codes.swift_code)
+RETURNING swift_code
+`
+
+	syncInsertBranchesQuery = `
+INSERT INTO branches (swift_code, headquarter)
+SELECT swift_code, NULL
+FROM swift_codes
+WHERE NOT is_headquarter
+  AND NOT EXISTS (SELECT 1 FROM branches WHERE branches.swift_code = swift_codes.swift_code)
+`
+)
+
+func TestSyncRecords(t *testing.T) {
+	hq := parser.SwiftRecord{ISO2Code: "PL", SwiftCode: "PKOPPLPWXXX", BankName: "PKO", Address: "Warsaw", Country: "Poland", IsHeadquarter: true}
+	branch := parser.SwiftRecord{ISO2Code: "PL", SwiftCode: "PKOPPLPW002", BankName: "PKO", Address: "Krakow", Country: "Poland"}
+
+	expectIncoming := func(mock sqlmock.Sqlmock, r parser.SwiftRecord) {
+		mock.ExpectExec(insertIncomingQuery).
+			WithArgs(r.ISO2Code, r.SwiftCode, r.BankName, r.Address, r.Country, r.IsHeadquarter,
+				r.CodeType, r.TownName, r.TimeZone).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	codes := func(values ...string) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"swift_code"})
+		for _, v := range values {
+			rows.AddRow(v)
+		}
+		return rows
+	}
+
+	t.Run("applies inserts, updates and deletes", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(createIncomingQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		expectIncoming(mock, hq)
+		expectIncoming(mock, branch)
+		mock.ExpectExec(detachBranchesQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(syncDeleteQuery).WillReturnRows(codes("PKOPPLPW003", "OLDBPLPWXXX"))
+		mock.ExpectQuery(syncUpdateQuery).WithArgs(nil).WillReturnRows(codes("PKOPPLPWXXX"))
+		mock.ExpectQuery(syncInsertQuery).WithArgs(nil).WillReturnRows(codes("PKOPPLPW002"))
+		mock.ExpectExec(syncInsertBranchesQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(linkOrphanBranchesQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		summary, err := SyncRecords(t.Context(), db, []parser.SwiftRecord{hq, branch})
+		require.NoError(t, err)
+		require.Equal(t, []string{"PKOPPLPW002"}, summary.Inserted)
+		require.Equal(t, []string{"PKOPPLPWXXX"}, summary.Updated)
+		require.Equal(t, []string{"PKOPPLPW003", "OLDBPLPWXXX"}, summary.Deleted)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nothing changed", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(createIncomingQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		expectIncoming(mock, hq)
+		mock.ExpectExec(detachBranchesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(syncDeleteQuery).WillReturnRows(codes())
+		mock.ExpectQuery(syncUpdateQuery).WithArgs(nil).WillReturnRows(codes())
+		mock.ExpectQuery(syncInsertQuery).WithArgs(nil).WillReturnRows(codes())
+		mock.ExpectExec(syncInsertBranchesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(linkOrphanBranchesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		summary, err := SyncRecords(t.Context(), db, []parser.SwiftRecord{hq})
+		require.NoError(t, err)
+		require.Empty(t, summary.Inserted)
+		require.Empty(t, summary.Updated)
+		require.Empty(t, summary.Deleted)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failure rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(createIncomingQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		expectIncoming(mock, hq)
+		mock.ExpectExec(detachBranchesQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(syncDeleteQuery).WillReturnError(errors.New("violates foreign key constraint"))
+		mock.ExpectRollback()
+
+		_, err = SyncRecords(t.Context(), db, []parser.SwiftRecord{hq})
+		require.ErrorContains(t, err, "foreign key")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+var (
+	diffInsertedQuery = `
+SELECT swift_code
+FROM incoming_swift_codes
+WHERE NOT EXISTS (SELECT 1 FROM swift_codes WHERE swift_codes.swift_code = incoming_swift_codes.swift_code)
+ORDER BY swift_code
+`
+
+	diffDeletedQuery = `
+SELECT swift_code
+FROM swift_codes
+WHERE NOT EXISTS (SELECT 1 FROM incoming_swift_codes WHERE incoming_swift_codes.swift_code = swift_codes.swift_code)
+ORDER BY swift_code
+`
+
+	diffUpdatedQuery = `
+SELECT s.swift_code,
+       s.country_iso2_code, s.bank_name, s.address, s.country_name, s.code_type, s.town_name, s.time_zone,
+       i.country_iso2_code, i.bank_name, i.address, i.country_name, i.code_type, i.town_name, i.time_zone
+FROM swift_codes s
+JOIN incoming_swift_codes i ON i.swift_code = s.swift_code
+WHERE (s.country_iso2_code, s.bank_name, s.address, s.country_name,
+       s.is_headquarter, s.code_type, s.town_name, s.time_zone)
+      IS DISTINCT FROM
+      (i.country_iso2_code, i.bank_name, i.address, i.country_name,
+       i.is_headquarter, i.code_type, i.town_name, i.time_zone)
+ORDER BY s.swift_code
+`
+
+	diffRelinkedQuery = `
+SELECT branches.swift_code, branches.headquarter, hq.swift_code
+FROM branches
+JOIN incoming_swift_codes i ON i.swift_code = branches.swift_code
+LEFT JOIN incoming_swift_codes hq
+       ON hq.is_headquarter AND hq.swift_code = LEFT(branches.swift_code, 8) || 'XXX'
+WHERE branches.headquarter IS DISTINCT FROM hq.swift_code
+ORDER BY branches.swift_code
+`
+)
+
+func TestDryRun(t *testing.T) {
+	hq := parser.SwiftRecord{ISO2Code: "PL", SwiftCode: "PKOPPLPWXXX", BankName: "PKO BP", Address: "Warsaw", Country: "Poland", IsHeadquarter: true}
+	branch := parser.SwiftRecord{ISO2Code: "PL", SwiftCode: "PKOPPLPW002", BankName: "PKO", Address: "Krakow", Country: "Poland"}
+
+	stream := func(records ...parser.SwiftRecord) func(func(parser.SwiftRecord) error) error {
+		return func(add func(parser.SwiftRecord) error) error {
+			for _, r := range records {
+				if err := add(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	t.Run("computes diff and rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(createIncomingQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		for _, r := range []parser.SwiftRecord{hq, branch} {
+			mock.ExpectExec(insertIncomingQuery).
+				WithArgs(r.ISO2Code, r.SwiftCode, r.BankName, r.Address, r.Country, r.IsHeadquarter,
+					r.CodeType, r.TownName, r.TimeZone).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		mock.ExpectQuery(diffInsertedQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code"}).AddRow("PKOPPLPWXXX"))
+		mock.ExpectQuery(diffDeletedQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code"}).AddRow("OLDBPLPWXXX"))
+		mock.ExpectQuery(diffUpdatedQuery).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"swift_code",
+				"country_iso2_code", "bank_name", "address", "country_name", "code_type", "town_name", "time_zone",
+				"country_iso2_code", "bank_name", "address", "country_name", "code_type", "town_name", "time_zone",
+			}).AddRow("PKOPPLPW002",
+				"PL", "PKO", "Old street", "Poland", "BIC11", "KRAKOW", "Europe/Warsaw",
+				"PL", "PKO", "Krakow", "Poland", "BIC11", "KRAKOW", "Europe/Warsaw"))
+		mock.ExpectQuery(diffRelinkedQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"swift_code", "headquarter", "swift_code"}).
+				AddRow("PKOPPLPW002", nil, "PKOPPLPWXXX"))
+		mock.ExpectRollback()
+
+		diff, err := DryRun(t.Context(), db, stream(hq, branch))
+		require.NoError(t, err)
+		require.Equal(t, []string{"PKOPPLPWXXX"}, diff.Inserted)
+		require.Equal(t, []string{"OLDBPLPWXXX"}, diff.Deleted)
+		require.Len(t, diff.Updated, 1)
+		require.Equal(t, []FieldChange{{Field: "address", Old: "Old street", New: "Krakow"}}, diff.Updated[0].Changes)
+		require.Len(t, diff.Relinked, 1)
+		require.Nil(t, diff.Relinked[0].OldHeadquarter)
+		require.Equal(t, "PKOPPLPWXXX", *diff.Relinked[0].NewHeadquarter)
+		require.NoError(t, mock.ExpectationsWereMet())
+
+		var out strings.Builder
+		require.NoError(t, diff.WriteText(&out))
+		require.Equal(t, "1 inserted, 1 updated, 1 deleted, 1 relinked\n"+
+			"+ PKOPPLPWXXX\n"+
+			"~ PKOPPLPW002\n"+
+			"    address: \"Old street\" -> \"Krakow\"\n"+
+			"- OLDBPLPWXXX\n"+
+			"> PKOPPLPW002: (none) -> PKOPPLPWXXX\n", out.String())
+	})
+
+	t.Run("stream error rolls back", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(createIncomingQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		_, err = DryRun(t.Context(), db, func(add func(parser.SwiftRecord) error) error {
+			return errors.New("bad file")
+		})
+		require.ErrorContains(t, err, "bad file")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
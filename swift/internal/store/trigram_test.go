@@ -0,0 +1,13 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity("Word", "word"))
+	assert.InDelta(t, 0.363636, Similarity("word", "two words"), 0.0001)
+	assert.Equal(t, 0.0, Similarity("", "word"))
+}
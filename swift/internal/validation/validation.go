@@ -0,0 +1,183 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mbartnicki80/swift/internal/country"
+	"github.com/mbartnicki80/swift/internal/model"
+)
+
+const HeadquarterBranch = "XXX"
+
+// MaxCodeTypeLength is the width of the swift_codes.code_type column.
+const MaxCodeTypeLength = 5
+
+// CheckCodeType rejects code types that do not fit the code_type column.
+func CheckCodeType(codeType string) error {
+	if n := utf8.RuneCountInString(codeType); n > MaxCodeTypeLength {
+		return fmt.Errorf("must be at most %d characters, got %d", MaxCodeTypeLength, n)
+	}
+	return nil
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + " " + fieldErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *Errors) add(field, message string) {
+	*e = append(*e, FieldError{Field: field, Message: message})
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func NormalizeBIC(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CheckBIC validates an already normalized code against ISO 9362: a 4-letter
+// institution code, an ISO 3166 country code, a 2-character location code and
+// an optional 3-character branch code.
+func CheckBIC(code string) error {
+	if len(code) != 8 && len(code) != 11 {
+		return fmt.Errorf("must be 8 or 11 characters, got %d", len(code))
+	}
+	if !isLetters(code[:4]) {
+		return errors.New("institution code (characters 1-4) must be letters")
+	}
+	if !isLetters(code[4:6]) {
+		return errors.New("country code (characters 5-6) must be letters")
+	}
+	if !country.IsKnown(code[4:6]) {
+		return fmt.Errorf("unknown country code %q in characters 5-6", code[4:6])
+	}
+	if !isAlphanumeric(code[6:8]) {
+		return errors.New("location code (characters 7-8) must be letters or digits")
+	}
+	if !isAlphanumeric(code[8:]) {
+		return errors.New("branch code (characters 9-11) must be letters or digits")
+	}
+	return nil
+}
+
+func IsHeadquarterCode(code string) bool {
+	return strings.HasSuffix(code, HeadquarterBranch)
+}
+
+func checkHeadquarter(errs *Errors, code string, isHeadquarter bool) {
+	if isHeadquarter && !IsHeadquarterCode(code) {
+		errs.add("isHeadquarter", "must be false for codes not ending in XXX")
+	}
+	if !isHeadquarter && IsHeadquarterCode(code) {
+		errs.add("isHeadquarter", "must be true for codes ending in XXX")
+	}
+}
+
+func checkCountryISO2(errs *Errors, code, iso2 string) {
+	if len(iso2) != 2 || !isLetters(iso2) {
+		errs.add("countryISO2", "must be a 2-letter ISO 3166 code")
+		return
+	}
+	if len(code) >= 6 && code[4:6] != iso2 {
+		errs.add("countryISO2", fmt.Sprintf("must match SWIFT code characters 5-6 %q", code[4:6]))
+	}
+}
+
+// SwiftCode normalizes code in place and reports every invalid field. An
+// 8-character code is expanded with the XXX branch code, and an empty country
+// name is filled in from the ISO 3166 table.
+func SwiftCode(code *model.SwiftCode) Errors {
+	var errs Errors
+
+	code.SwiftCode = NormalizeBIC(code.SwiftCode)
+	code.CountryISO2 = strings.ToUpper(strings.TrimSpace(code.CountryISO2))
+	code.CountryName = strings.ToUpper(strings.TrimSpace(code.CountryName))
+
+	if err := CheckBIC(code.SwiftCode); err != nil {
+		errs.add("swiftCode", err.Error())
+	} else {
+		if len(code.SwiftCode) == 8 {
+			code.SwiftCode += HeadquarterBranch
+		}
+		checkHeadquarter(&errs, code.SwiftCode, code.IsHeadquarter)
+	}
+	checkCountryISO2(&errs, code.SwiftCode, code.CountryISO2)
+
+	if code.CountryName == "" {
+		if name, ok := country.Name(code.CountryISO2); ok {
+			code.CountryName = name
+		} else {
+			errs.add("countryName", "is required")
+		}
+	}
+	if strings.TrimSpace(code.BankName) == "" {
+		errs.add("bankName", "is required")
+	}
+	if err := CheckCodeType(code.CodeType); err != nil {
+		errs.add("codeType", err.Error())
+	}
+	return errs
+}
+
+// Patch normalizes the fields set in patch and validates them against the
+// code being patched, which cannot itself be changed.
+func Patch(swiftCode string, patch *model.SwiftCodePatch) Errors {
+	var errs Errors
+
+	if patch.SwiftCode != nil && NormalizeBIC(*patch.SwiftCode) != swiftCode {
+		errs.add("swiftCode", "cannot be changed")
+	}
+	if patch.IsHeadquarter != nil {
+		checkHeadquarter(&errs, swiftCode, *patch.IsHeadquarter)
+	}
+	if patch.CountryISO2 != nil {
+		iso2 := strings.ToUpper(strings.TrimSpace(*patch.CountryISO2))
+		patch.CountryISO2 = &iso2
+		checkCountryISO2(&errs, swiftCode, iso2)
+	}
+	if patch.CountryName != nil {
+		name := strings.ToUpper(strings.TrimSpace(*patch.CountryName))
+		patch.CountryName = &name
+		if name == "" {
+			errs.add("countryName", "cannot be empty")
+		}
+	}
+	if patch.BankName != nil && strings.TrimSpace(*patch.BankName) == "" {
+		errs.add("bankName", "cannot be empty")
+	}
+	if patch.CodeType != nil {
+		if err := CheckCodeType(*patch.CodeType); err != nil {
+			errs.add("codeType", err.Error())
+		}
+	}
+	return errs
+}
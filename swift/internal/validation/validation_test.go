@@ -0,0 +1,85 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/mbartnicki80/swift/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fields(errs Errors) []string {
+	var names []string
+	for _, fieldErr := range errs {
+		names = append(names, fieldErr.Field)
+	}
+	return names
+}
+
+func TestCheckBIC(t *testing.T) {
+	tests := []struct {
+		code    string
+		wantErr string
+	}{
+		{code: "DEUTDEFF"},
+		{code: "DEUTDEFFXXX"},
+		{code: "BREXPLPW0A1"},
+		{code: "ABC", wantErr: "must be 8 or 11 characters, got 3"},
+		{code: "DEUTDEFF5", wantErr: "must be 8 or 11 characters, got 9"},
+		{code: "DE1TDEFFXXX", wantErr: "institution code"},
+		{code: "DEUTD1FFXXX", wantErr: "country code (characters 5-6) must be letters"},
+		{code: "DEUTQQFFXXX", wantErr: "unknown country code"},
+		{code: "DEUTDEF-XXX", wantErr: "location code"},
+		{code: "DEUTDEFFX-X", wantErr: "branch code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			err := CheckBIC(tt.code)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSwiftCode(t *testing.T) {
+	t.Run("normalizes a valid code", func(t *testing.T) {
+		code := model.SwiftCode{SwiftCode: " deutdeff ", CountryISO2: "de", BankName: "Deutsche Bank", IsHeadquarter: true}
+		require.Empty(t, SwiftCode(&code))
+		assert.Equal(t, "DEUTDEFFXXX", code.SwiftCode)
+		assert.Equal(t, "DE", code.CountryISO2)
+		assert.Equal(t, "GERMANY", code.CountryName)
+	})
+
+	t.Run("short code does not panic", func(t *testing.T) {
+		code := model.SwiftCode{SwiftCode: "ABC", CountryISO2: "DE", BankName: "Bank"}
+		errs := SwiftCode(&code)
+		assert.Equal(t, []string{"swiftCode"}, fields(errs))
+	})
+
+	t.Run("reports every invalid field", func(t *testing.T) {
+		code := model.SwiftCode{SwiftCode: "DEUTDEFF500", CountryISO2: "FR", CountryName: "Germany", IsHeadquarter: true, CodeType: "BIC11X"}
+		errs := SwiftCode(&code)
+		assert.Equal(t, []string{"isHeadquarter", "countryISO2", "bankName", "codeType"}, fields(errs))
+		assert.Contains(t, errs.Error(), `countryISO2 must match SWIFT code characters 5-6 "DE"`)
+	})
+
+	t.Run("headquarter flag required for XXX codes", func(t *testing.T) {
+		code := model.SwiftCode{SwiftCode: "DEUTDEFFXXX", CountryISO2: "DE", BankName: "Deutsche Bank"}
+		assert.Equal(t, []string{"isHeadquarter"}, fields(SwiftCode(&code)))
+	})
+}
+
+func TestPatch(t *testing.T) {
+	iso2, changed, empty, hq, codeType := "de", "DEUTDEFF600", " ", true, "BRANCH"
+
+	patch := model.SwiftCodePatch{CountryISO2: &iso2}
+	require.Empty(t, Patch("DEUTDEFF500", &patch))
+	assert.Equal(t, "DE", *patch.CountryISO2)
+
+	patch = model.SwiftCodePatch{SwiftCode: &changed, BankName: &empty, IsHeadquarter: &hq, CodeType: &codeType}
+	assert.Equal(t, []string{"swiftCode", "isHeadquarter", "bankName", "codeType"}, fields(Patch("DEUTDEFF500", &patch)))
+}
@@ -0,0 +1,3 @@
+ALTER TABLE swift_codes DROP COLUMN IF EXISTS time_zone;
+ALTER TABLE swift_codes DROP COLUMN IF EXISTS town_name;
+ALTER TABLE swift_codes DROP COLUMN IF EXISTS code_type;
@@ -0,0 +1,3 @@
+ALTER TABLE swift_codes ADD COLUMN IF NOT EXISTS code_type VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE swift_codes ADD COLUMN IF NOT EXISTS town_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE swift_codes ADD COLUMN IF NOT EXISTS time_zone TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_swift_codes_import;
+ALTER TABLE swift_codes DROP COLUMN IF EXISTS import_id;
+DROP TABLE IF EXISTS imports;
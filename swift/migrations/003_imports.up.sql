@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS imports (
+    id BIGSERIAL PRIMARY KEY,
+    source_filename TEXT NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    format TEXT NOT NULL,
+    mode TEXT NOT NULL,
+    operator TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'running',
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    accepted_rows INTEGER NOT NULL DEFAULT 0,
+    rejected_rows INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+ALTER TABLE swift_codes ADD COLUMN IF NOT EXISTS import_id BIGINT REFERENCES imports(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_swift_codes_import ON swift_codes(import_id);
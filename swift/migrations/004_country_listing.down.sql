@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_country_iso2 ON swift_codes(country_iso2_code);
+DROP INDEX IF EXISTS idx_country_bank_name;
+DROP INDEX IF EXISTS idx_country_swift_code;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_country_swift_code ON swift_codes(country_iso2_code, swift_code);
+CREATE INDEX IF NOT EXISTS idx_country_bank_name ON swift_codes(country_iso2_code, bank_name, swift_code);
+DROP INDEX IF EXISTS idx_country_iso2;
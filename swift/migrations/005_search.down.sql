@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_swift_codes_search;
+ALTER TABLE swift_codes DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,8 @@
+ALTER TABLE swift_codes ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(bank_name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(town_name, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(address, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_swift_codes_search ON swift_codes USING GIN (search_vector);
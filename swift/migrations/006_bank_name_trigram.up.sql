@@ -0,0 +1,3 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_swift_codes_bank_name_trgm ON swift_codes USING GIN (bank_name gin_trgm_ops);
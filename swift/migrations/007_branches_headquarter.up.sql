@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_branches_headquarter ON branches(headquarter);